@@ -0,0 +1,489 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+
+	"crawler/crawl"
+	"crawler/report"
+)
+
+// duration reads "500ms"-style strings from the config file.
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+// stringList is a repeatable flag. The first use on the command line
+// replaces any values from the config file.
+type stringList struct {
+	values *[]string
+	set    bool
+}
+
+func (l *stringList) String() string {
+	if l.values == nil {
+		return ""
+	}
+	return strings.Join(*l.values, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	if !l.set {
+		*l.values = nil
+		l.set = true
+	}
+	*l.values = append(*l.values, value)
+	return nil
+}
+
+// cliConfig holds every crawl setting. It can be loaded from a JSON file
+// with --config; flags given on the command line take precedence.
+type cliConfig struct {
+	URL                 string   `json:"url"`
+	Concurrency         int      `json:"concurrency"`
+	MaxPages            int      `json:"max_pages"`
+	MaxDepth            int      `json:"max_depth"`
+	Delay               duration `json:"delay"`
+	Rate                float64  `json:"rate"`
+	MaxInFlight         int      `json:"max_in_flight"`
+	Retries             int      `json:"retries"`
+	Output              string   `json:"output"`
+	Format              string   `json:"format"`
+	UserAgent           string   `json:"user_agent"`
+	Timeout             duration `json:"timeout"`
+	ConnectTimeout      duration `json:"connect_timeout"`
+	HeaderTimeout       duration `json:"header_timeout"`
+	MaxBodySize         int64    `json:"max_body_size"`
+	Proxy               string   `json:"proxy"`
+	Headers             []string `json:"headers"`
+	Cookies             []string `json:"cookies"`
+	BasicAuth           string   `json:"basic_auth"`
+	Include             []string `json:"include"`
+	Exclude             []string `json:"exclude"`
+	AllowSubdomains     bool     `json:"allow_subdomains"`
+	SameDomain          bool     `json:"same_domain"`
+	AllowHosts          []string `json:"allow_hosts"`
+	PathPrefix          string   `json:"path_prefix"`
+	IncludeScheme       bool     `json:"include_scheme"`
+	IgnoreQuery         bool     `json:"ignore_query"`
+	StripParams         []string `json:"strip_params"`
+	CacheDir            string   `json:"cache_dir"`
+	Checkpoint          string   `json:"checkpoint"`
+	CheckpointInterval  duration `json:"checkpoint_interval"`
+	Resume              bool     `json:"resume"`
+	CheckAssets         bool     `json:"check_assets"`
+	AssetConcurrency    int      `json:"asset_concurrency"`
+	MaxAssetSize        int64    `json:"max_asset_size"`
+	CheckExternal       bool     `json:"check_external"`
+	ExternalConcurrency int      `json:"external_concurrency"`
+	WARC                string   `json:"warc"`
+	Graph               []string `json:"graph"`
+	DuplicateThreshold  float64  `json:"duplicate_threshold"`
+	Quiet               bool     `json:"quiet"`
+	StatsJSON           string   `json:"stats_json"`
+	LogLevel            string   `json:"log_level"`
+	LogFormat           string   `json:"log_format"`
+	MetricsAddr         string   `json:"metrics_addr"`
+
+	// Extractors are only read from the config file.
+	Extractors []extractorConfig `json:"extractors"`
+}
+
+// extractorConfig is a CSS selector rule whose matches are reported as an
+// extra page field.
+type extractorConfig struct {
+	Name     string `json:"name"`
+	Selector string `json:"selector"`
+	Attr     string `json:"attr"`
+	All      bool   `json:"all"`
+}
+
+func defaultCLIConfig() cliConfig {
+	return cliConfig{
+		Concurrency: 5,
+		MaxPages:    100,
+		MaxDepth:    -1,
+		Delay:       duration(500 * time.Millisecond),
+		MaxInFlight: 2,
+		Retries:     2,
+		Output:      "report.csv",
+		Format:      "csv",
+		UserAgent:   crawl.DefaultUserAgent,
+		Timeout:     duration(30 * time.Second),
+		LogLevel:    "warn",
+		LogFormat:   "text",
+		StripParams: crawl.DefaultNormalizer().StripParams,
+
+		ConnectTimeout:      duration(10 * time.Second),
+		HeaderTimeout:       duration(15 * time.Second),
+		MaxBodySize:         10 << 20,
+		CheckpointInterval:  duration(30 * time.Second),
+		AssetConcurrency:    5,
+		ExternalConcurrency: 5,
+		MaxAssetSize:        1 << 20,
+		DuplicateThreshold:  0.95,
+	}
+}
+
+func newFlagSet(cfg *cliConfig, configPath *string) *flag.FlagSet {
+	fs := flag.NewFlagSet("crawler", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: crawler [flags] <BASE_URL>\n")
+		fmt.Fprintf(fs.Output(), "       crawler diff [flags] <OLD_REPORT> <NEW_REPORT>\n")
+		fmt.Fprintf(fs.Output(), "       crawler read-warc [flags] <WARC_FILE>...\n")
+		fmt.Fprintf(fs.Output(), "       crawler serve [flags]\n\n")
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(configPath, "config", "", "JSON file with crawl settings; flags override it")
+	fs.IntVar(&cfg.Concurrency, "concurrency", cfg.Concurrency, "number of concurrent workers")
+	fs.IntVar(&cfg.MaxPages, "max-pages", cfg.MaxPages, "stop after crawling this many pages")
+	fs.IntVar(&cfg.MaxDepth, "max-depth", cfg.MaxDepth, "maximum link depth from the start URL, -1 for unlimited")
+	fs.DurationVar((*time.Duration)(&cfg.Delay), "delay", time.Duration(cfg.Delay), "minimum time between requests to the same host, robots.txt Crawl-delay wins")
+	fs.Float64Var(&cfg.Rate, "rate", cfg.Rate, "requests per second to the same host, overrides --delay")
+	fs.IntVar(&cfg.MaxInFlight, "max-in-flight", cfg.MaxInFlight, "requests to the same host at once, 0 for no limit")
+	fs.IntVar(&cfg.Retries, "retries", cfg.Retries, "retries of a page after a network error, 429 or temporary server error")
+	fs.StringVar(&cfg.Output, "output", cfg.Output, "report file")
+	fs.StringVar(&cfg.Format, "format", cfg.Format, "report format: "+strings.Join(report.Formats(), ", "))
+	fs.StringVar(&cfg.UserAgent, "user-agent", cfg.UserAgent, "User-Agent header and robots.txt agent")
+	fs.DurationVar((*time.Duration)(&cfg.Timeout), "timeout", time.Duration(cfg.Timeout), "timeout for a single request, body included")
+	fs.DurationVar((*time.Duration)(&cfg.ConnectTimeout), "connect-timeout", time.Duration(cfg.ConnectTimeout), "timeout for connecting to a host")
+	fs.DurationVar((*time.Duration)(&cfg.HeaderTimeout), "header-timeout", time.Duration(cfg.HeaderTimeout), "timeout for the response headers once the request is sent")
+	fs.Int64Var(&cfg.MaxBodySize, "max-body-size", cfg.MaxBodySize, "read at most this many bytes of a page, 0 for no limit")
+	fs.StringVar(&cfg.Proxy, "proxy", cfg.Proxy, "proxy URL, default from HTTP_PROXY and HTTPS_PROXY")
+	fs.Var(&stringList{values: &cfg.Headers}, "header", `"Name: value" header sent to the crawled site (repeatable)`)
+	fs.Var(&stringList{values: &cfg.Cookies}, "cookie", `"name=value" cookies sent to the crawled site (repeatable)`)
+	fs.StringVar(&cfg.BasicAuth, "basic-auth", cfg.BasicAuth, `"user:password" for HTTP basic auth on the crawled site`)
+	fs.Var(&stringList{values: &cfg.Include}, "include", "only crawl URLs matching this regex (repeatable)")
+	fs.Var(&stringList{values: &cfg.Exclude}, "exclude", "skip URLs matching this regex (repeatable)")
+	fs.BoolVar(&cfg.AllowSubdomains, "allow-subdomains", cfg.AllowSubdomains, "also crawl subdomains of the start host")
+	fs.BoolVar(&cfg.SameDomain, "same-domain", cfg.SameDomain, "also crawl the other hosts of the start host's registrable domain")
+	fs.Var(&stringList{values: &cfg.AllowHosts}, "allow-host", "also crawl this host, *.example.com for its subdomains (repeatable)")
+	fs.StringVar(&cfg.PathPrefix, "path-prefix", cfg.PathPrefix, "only crawl URLs whose path starts with this prefix")
+	fs.BoolVar(&cfg.IncludeScheme, "include-scheme", cfg.IncludeScheme, "treat http and https URLs as different pages")
+	fs.BoolVar(&cfg.IgnoreQuery, "ignore-query", cfg.IgnoreQuery, "ignore the query string when identifying pages")
+	fs.StringVar(&cfg.CacheDir, "cache-dir", cfg.CacheDir, "keep responses here and only refetch pages that changed")
+	fs.StringVar(&cfg.Checkpoint, "checkpoint", cfg.Checkpoint, "save the crawl state to this file so it can be resumed")
+	fs.DurationVar((*time.Duration)(&cfg.CheckpointInterval), "checkpoint-interval", time.Duration(cfg.CheckpointInterval), "how often to save the checkpoint, 0 for only at the end")
+	fs.BoolVar(&cfg.Resume, "resume", cfg.Resume, "continue the crawl saved in the checkpoint file")
+	fs.Float64Var(&cfg.DuplicateThreshold, "duplicate-threshold", cfg.DuplicateThreshold, "similarity from 0 to 1 from which pages count as near-duplicates")
+	fs.StringVar(&cfg.WARC, "warc", cfg.WARC, "archive every page request and response in this WARC file")
+	fs.Var(&stringList{values: &cfg.Graph}, "graph", "export the link graph to this .dot, .graphml or .json file (repeatable)")
+	fs.BoolVar(&cfg.CheckAssets, "check-assets", cfg.CheckAssets, "check images, scripts, stylesheets and other assets after the crawl")
+	fs.IntVar(&cfg.AssetConcurrency, "asset-concurrency", cfg.AssetConcurrency, "number of concurrent asset checks")
+	fs.Int64Var(&cfg.MaxAssetSize, "max-asset-size", cfg.MaxAssetSize, "flag assets larger than this many bytes, 0 for no limit")
+	fs.BoolVar(&cfg.CheckExternal, "check-external", cfg.CheckExternal, "check the status of out-of-scope links after the crawl, without crawling them")
+	fs.IntVar(&cfg.ExternalConcurrency, "external-concurrency", cfg.ExternalConcurrency, "number of concurrent external link checks")
+	fs.BoolVar(&cfg.Quiet, "quiet", cfg.Quiet, "print errors only, no progress or summary")
+	fs.StringVar(&cfg.StatsJSON, "stats-json", cfg.StatsJSON, "write crawl statistics to this JSON file")
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "log level: debug, info (every fetched page), warn or error")
+	fs.StringVar(&cfg.LogFormat, "log-format", cfg.LogFormat, "log format: text or json")
+	fs.StringVar(&cfg.MetricsAddr, "metrics-addr", cfg.MetricsAddr, "serve Prometheus metrics on this address at /metrics during the crawl")
+	fs.Var(&stringList{values: &cfg.StripParams}, "strip-param", "query parameter to drop, trailing * matches a prefix (repeatable, replaces the defaults)")
+	return fs
+}
+
+// parseArgs builds the config from defaults, the optional --config file and
+// the command line, in that order of precedence.
+func parseArgs(args []string) (cliConfig, error) {
+	cfg := defaultCLIConfig()
+	var configPath string
+	fs := newFlagSet(&cfg, &configPath)
+	if err := fs.Parse(args); err != nil {
+		return cliConfig{}, err
+	}
+
+	if configPath != "" {
+		cfg = defaultCLIConfig()
+		if err := loadConfigFile(configPath, &cfg); err != nil {
+			return cliConfig{}, fmt.Errorf("reading config %s: %w", configPath, err)
+		}
+		// parse again so flags override the file
+		fs = newFlagSet(&cfg, &configPath)
+		fs.SetOutput(io.Discard)
+		if err := fs.Parse(args); err != nil {
+			return cliConfig{}, err
+		}
+	}
+
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		cfg.URL = fs.Arg(0)
+	default:
+		return cliConfig{}, errors.New("too many arguments provided")
+	}
+	if cfg.URL == "" {
+		return cliConfig{}, errors.New("no website provided")
+	}
+
+	return cfg, cfg.validate()
+}
+
+func loadConfigFile(path string, cfg *cliConfig) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return decodeConfig(file, cfg)
+}
+
+// decodeConfig reads JSON crawl settings over cfg. Unknown keys are an
+// error, so typos don't go unnoticed.
+func decodeConfig(r io.Reader, cfg *cliConfig) error {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(cfg)
+}
+
+func (cfg cliConfig) validate() error {
+	if cfg.Concurrency <= 0 {
+		return errors.New("invalid concurrency value")
+	}
+	if cfg.MaxPages <= 0 {
+		return errors.New("invalid max pages value")
+	}
+	if cfg.Delay < 0 {
+		return errors.New("invalid delay value")
+	}
+	if cfg.Rate < 0 {
+		return errors.New("invalid rate value")
+	}
+	if cfg.MaxInFlight < 0 {
+		return errors.New("invalid max in-flight value")
+	}
+	if cfg.Retries < 0 {
+		return errors.New("invalid retries value")
+	}
+	if cfg.Timeout < 0 || cfg.ConnectTimeout < 0 || cfg.HeaderTimeout < 0 {
+		return errors.New("invalid timeout value")
+	}
+	if cfg.MaxBodySize < 0 {
+		return errors.New("invalid max body size value")
+	}
+	if cfg.Proxy != "" {
+		if proxyURL, err := url.Parse(cfg.Proxy); err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return fmt.Errorf("invalid proxy URL %q", cfg.Proxy)
+		}
+	}
+	for _, header := range cfg.Headers {
+		if name, _, found := strings.Cut(header, ":"); !found || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("invalid header %q, expected \"Name: value\"", header)
+		}
+	}
+	for _, cookie := range cfg.Cookies {
+		if _, err := http.ParseCookie(cookie); err != nil {
+			return fmt.Errorf("invalid cookie %q: %w", cookie, err)
+		}
+	}
+	if cfg.BasicAuth != "" && !strings.Contains(cfg.BasicAuth, ":") {
+		return errors.New(`invalid basic auth, expected "user:password"`)
+	}
+	if cfg.CheckpointInterval < 0 {
+		return errors.New("invalid checkpoint interval value")
+	}
+	if cfg.Resume && cfg.Checkpoint == "" {
+		return errors.New("--resume needs --checkpoint")
+	}
+	if cfg.AssetConcurrency <= 0 {
+		return errors.New("invalid asset concurrency value")
+	}
+	if cfg.MaxAssetSize < 0 {
+		return errors.New("invalid max asset size value")
+	}
+	if cfg.ExternalConcurrency <= 0 {
+		return errors.New("invalid external concurrency value")
+	}
+	if cfg.PathPrefix != "" && !strings.HasPrefix(cfg.PathPrefix, "/") {
+		return errors.New(`path prefix must start with "/"`)
+	}
+	if cfg.DuplicateThreshold <= 0 || cfg.DuplicateThreshold > 1 {
+		return errors.New("invalid duplicate threshold value")
+	}
+	if _, err := report.NewWriter(cfg.Format); err != nil {
+		return err
+	}
+	if _, err := newLogger(io.Discard, cfg.LogLevel, cfg.LogFormat); err != nil {
+		return err
+	}
+	for _, path := range cfg.Graph {
+		if _, err := report.GraphWriterFor(path); err != nil {
+			return err
+		}
+	}
+	for _, extractor := range cfg.Extractors {
+		if extractor.Name == "" {
+			return errors.New("extractor without name")
+		}
+		if _, err := cascadia.Compile(extractor.Selector); err != nil {
+			return fmt.Errorf("invalid selector for extractor %s: %w", extractor.Name, err)
+		}
+	}
+	return nil
+}
+
+// requestsPerSecond is the rate limit per host: --rate, or else one
+// request every --delay. 0 means no limit.
+func (cfg cliConfig) requestsPerSecond() float64 {
+	if cfg.Rate > 0 || cfg.Delay == 0 {
+		return cfg.Rate
+	}
+	return float64(time.Second) / float64(cfg.Delay)
+}
+
+// crawlOptions turns a validated config into crawl options.
+func (cfg cliConfig) crawlOptions() ([]crawl.Option, error) {
+	include, err := compilePatterns(cfg.Include)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include pattern: %w", err)
+	}
+	exclude, err := compilePatterns(cfg.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exclude pattern: %w", err)
+	}
+
+	opts := []crawl.Option{
+		crawl.WithConcurrency(cfg.Concurrency),
+		crawl.WithMaxPages(cfg.MaxPages),
+		crawl.WithMaxDepth(cfg.MaxDepth),
+		crawl.WithRateLimit(cfg.requestsPerSecond(), cfg.MaxInFlight),
+		crawl.WithRetries(cfg.Retries),
+		crawl.WithUserAgent(cfg.UserAgent),
+		crawl.WithTimeouts(time.Duration(cfg.ConnectTimeout), time.Duration(cfg.HeaderTimeout), time.Duration(cfg.Timeout)),
+		crawl.WithMaxBodySize(cfg.MaxBodySize),
+		crawl.WithInclude(include...),
+		crawl.WithExclude(exclude...),
+		crawl.WithAllowSubdomains(cfg.AllowSubdomains),
+		crawl.WithSameDomain(cfg.SameDomain),
+		crawl.WithAllowedHosts(cfg.AllowHosts...),
+		crawl.WithPathPrefix(cfg.PathPrefix),
+		crawl.WithNormalizer(cfg.normalizer()),
+		crawl.WithExtractors(cfg.extractors()...),
+		crawl.WithDuplicateThreshold(cfg.DuplicateThreshold),
+	}
+	opts = append(opts, cfg.fetchOptions()...)
+	if cfg.CacheDir != "" {
+		opts = append(opts, crawl.WithCache(cfg.CacheDir))
+	}
+	if cfg.Checkpoint != "" {
+		opts = append(opts, crawl.WithCheckpoint(cfg.Checkpoint, time.Duration(cfg.CheckpointInterval)), crawl.WithResume(cfg.Resume))
+	}
+	if cfg.WARC != "" {
+		opts = append(opts, crawl.WithWARC(cfg.WARC))
+	}
+	if cfg.CheckAssets {
+		opts = append(opts, crawl.WithAssetCheck(cfg.AssetConcurrency), crawl.WithMaxAssetSize(cfg.MaxAssetSize))
+	}
+	if cfg.CheckExternal {
+		opts = append(opts, crawl.WithExternalLinkCheck(cfg.ExternalConcurrency))
+	}
+	return opts, nil
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// logger builds the logger of a validated config, writing to w. --quiet
+// drops everything below errors.
+func (cfg cliConfig) logger(w io.Writer) *slog.Logger {
+	level := cfg.LogLevel
+	if cfg.Quiet {
+		level = "error"
+	}
+	logger, _ := newLogger(w, level, cfg.LogFormat) // checked by validate
+	return logger
+}
+
+// newLogger returns a text or JSON logger writing to w from level on.
+func newLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+	var minLevel slog.Level
+	if err := minLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+	options := &slog.HandlerOptions{Level: minLevel}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q", format)
+}
+
+// fetchOptions turns the proxy, headers, cookies and basic auth of a
+// validated config into crawl options.
+func (cfg cliConfig) fetchOptions() []crawl.Option {
+	var opts []crawl.Option
+	if cfg.Proxy != "" {
+		proxyURL, _ := url.Parse(cfg.Proxy) // checked by validate
+		opts = append(opts, crawl.WithProxy(proxyURL))
+	}
+	for _, header := range cfg.Headers {
+		name, value, _ := strings.Cut(header, ":")
+		opts = append(opts, crawl.WithHeader(strings.TrimSpace(name), strings.TrimSpace(value)))
+	}
+	for _, cookie := range cfg.Cookies {
+		cookies, _ := http.ParseCookie(cookie)
+		opts = append(opts, crawl.WithCookies(cookies...))
+	}
+	if user, password, found := strings.Cut(cfg.BasicAuth, ":"); found {
+		opts = append(opts, crawl.WithBasicAuth(user, password))
+	}
+	return opts
+}
+
+// normalizer builds the URL normalizer from the config.
+func (cfg cliConfig) normalizer() *crawl.Normalizer {
+	normalizer := crawl.DefaultNormalizer()
+	normalizer.IncludeScheme = cfg.IncludeScheme
+	normalizer.IgnoreQuery = cfg.IgnoreQuery
+	normalizer.StripParams = cfg.StripParams
+	return normalizer
+}
+
+// extractors builds the custom extractors from the config.
+func (cfg cliConfig) extractors() []crawl.Extractor {
+	extractors := make([]crawl.Extractor, 0, len(cfg.Extractors))
+	for _, extractor := range cfg.Extractors {
+		extractors = append(extractors, crawl.SelectorExtractor{
+			Name:     extractor.Name,
+			Selector: extractor.Selector,
+			Attr:     extractor.Attr,
+			All:      extractor.All,
+		})
+	}
+	return extractors
+}
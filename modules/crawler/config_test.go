@@ -0,0 +1,164 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"crawler/crawl"
+)
+
+func TestParseArgs(t *testing.T) {
+	args := []string{
+		"--concurrency", "8",
+		"--max-pages", "20",
+		"--delay", "1s",
+		"--include", "/blog/",
+		"--include", "/docs/",
+		"--allow-subdomains",
+		"--quiet",
+		"--stats-json", "out/stats.json",
+		"https://blog.test.dev",
+	}
+
+	cfg, err := parseArgs(args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.URL != "https://blog.test.dev" {
+		t.Errorf("expected URL %q, got %q", "https://blog.test.dev", cfg.URL)
+	}
+	if cfg.Concurrency != 8 || cfg.MaxPages != 20 || cfg.MaxDepth != -1 {
+		t.Errorf("unexpected limits: %+v", cfg)
+	}
+	if time.Duration(cfg.Delay) != time.Second {
+		t.Errorf("expected delay %v, got %v", time.Second, time.Duration(cfg.Delay))
+	}
+	if cfg.requestsPerSecond() != 1 {
+		t.Errorf("expected 1 request per second, got %v", cfg.requestsPerSecond())
+	}
+	if !reflect.DeepEqual(cfg.Include, []string{"/blog/", "/docs/"}) {
+		t.Errorf("expected include patterns, got %v", cfg.Include)
+	}
+	if !cfg.AllowSubdomains {
+		t.Errorf("expected allow subdomains to be set")
+	}
+	if !cfg.Quiet || cfg.StatsJSON != "out/stats.json" {
+		t.Errorf("expected quiet with stats file, got %v and %q", cfg.Quiet, cfg.StatsJSON)
+	}
+}
+
+func TestParseArgsConfigFile(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "crawl.json")
+	configJSON := `{
+		"url": "https://blog.test.dev",
+		"concurrency": 2,
+		"max_pages": 50,
+		"timeout": "5s",
+		"exclude": ["\\.pdf$"],
+		"extractors": [{"name": "author", "selector": ".author"}]
+	}`
+	if err := os.WriteFile(configPath, []byte(configJSON), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg, err := parseArgs([]string{"--config", configPath, "--max-pages", "10"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.URL != "https://blog.test.dev" {
+		t.Errorf("expected URL from config file, got %q", cfg.URL)
+	}
+	if cfg.Concurrency != 2 {
+		t.Errorf("expected concurrency 2 from config file, got %d", cfg.Concurrency)
+	}
+	if cfg.MaxPages != 10 {
+		t.Errorf("expected flag to override max pages, got %d", cfg.MaxPages)
+	}
+	if time.Duration(cfg.Timeout) != 5*time.Second {
+		t.Errorf("expected timeout %v, got %v", 5*time.Second, time.Duration(cfg.Timeout))
+	}
+	if !reflect.DeepEqual(cfg.Exclude, []string{`\.pdf$`}) {
+		t.Errorf("expected exclude pattern from config file, got %v", cfg.Exclude)
+	}
+	expectedExtractors := []crawl.Extractor{crawl.SelectorExtractor{Name: "author", Selector: ".author"}}
+	if !reflect.DeepEqual(cfg.extractors(), expectedExtractors) {
+		t.Errorf("expected %v, got %v", expectedExtractors, cfg.extractors())
+	}
+}
+
+func TestParseArgsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "no website",
+			args: []string{"--max-pages", "10"},
+		},
+		{
+			name: "too many arguments",
+			args: []string{"https://a.dev", "https://b.dev"},
+		},
+		{
+			name: "invalid concurrency",
+			args: []string{"--concurrency", "0", "https://a.dev"},
+		},
+		{
+			name: "negative rate",
+			args: []string{"--rate", "-1", "https://a.dev"},
+		},
+		{
+			name: "negative retries",
+			args: []string{"--retries", "-1", "https://a.dev"},
+		},
+		{
+			name: "header without value",
+			args: []string{"--header", "X-Token", "https://a.dev"},
+		},
+		{
+			name: "invalid cookie",
+			args: []string{"--cookie", "no value", "https://a.dev"},
+		},
+		{
+			name: "proxy without scheme",
+			args: []string{"--proxy", "proxy.local:3128", "https://a.dev"},
+		},
+		{
+			name: "relative path prefix",
+			args: []string{"--path-prefix", "docs/", "https://a.dev"},
+		},
+		{
+			name: "unknown format",
+			args: []string{"--format", "xml", "https://a.dev"},
+		},
+		{
+			name: "unknown log level",
+			args: []string{"--log-level", "verbose", "https://a.dev"},
+		},
+		{
+			name: "unknown log format",
+			args: []string{"--log-format", "xml", "https://a.dev"},
+		},
+		{
+			name: "unknown graph format",
+			args: []string{"--graph", "graph.png", "https://a.dev"},
+		},
+		{
+			name: "invalid duplicate threshold",
+			args: []string{"--duplicate-threshold", "1.5", "https://a.dev"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := parseArgs(tc.args); err == nil {
+				t.Errorf("expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package crawl
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultMaxAssetSize is the size above which an asset is flagged as
+// oversized.
+const defaultMaxAssetSize = 1 << 20
+
+// Asset issues.
+const (
+	AssetBroken     = "broken"
+	AssetOversized  = "oversized"
+	AssetMissingAlt = "missing_alt"
+)
+
+// AssetResult records the check of one asset linked from the crawled
+// pages, e.g. an image, script or stylesheet.
+type AssetResult struct {
+	URL          string        `json:"url"`
+	Kind         LinkKind      `json:"kind"`
+	StatusCode   int           `json:"status_code"`
+	ContentType  string        `json:"content_type"`
+	Size         int64         `json:"size"` // -1 if unknown
+	ResponseTime time.Duration `json:"response_time_ns"`
+	Error        string        `json:"error,omitempty"`
+	LinkedFrom   []string      `json:"linked_from"`
+	MissingAltOn []string      `json:"missing_alt_on,omitempty"` // pages showing the image without alt text
+	Issues       []string      `json:"issues,omitempty"`
+}
+
+// Broken reports whether the asset failed with a 4xx/5xx status or could
+// not be fetched at all.
+func (a AssetResult) Broken() bool {
+	return a.StatusCode >= 400 || a.Error != ""
+}
+
+// collectAssets lists the asset links of the pages, one entry per
+// normalized URL, sorted by URL. Pages are visited in URL order so the
+// kind of an asset linked in different ways is stable.
+func collectAssets(pages map[string]PageData, normalizer *Normalizer) []*AssetResult {
+	sortedPages := make([]PageData, 0, len(pages))
+	for _, pageData := range pages {
+		sortedPages = append(sortedPages, pageData)
+	}
+	sort.Slice(sortedPages, func(i, j int) bool {
+		return sortedPages[i].URL < sortedPages[j].URL
+	})
+
+	assets := make(map[string]*AssetResult)
+	for _, pageData := range sortedPages {
+		for _, link := range pageData.Links {
+			if !link.IsAsset() {
+				continue
+			}
+			normalizedURL, err := normalizer.Normalize(link.URL)
+			if err != nil {
+				continue
+			}
+			asset, found := assets[normalizedURL]
+			if !found {
+				asset = &AssetResult{URL: link.URL, Kind: link.Kind, Size: -1}
+				assets[normalizedURL] = asset
+			}
+			if len(asset.LinkedFrom) == 0 || asset.LinkedFrom[len(asset.LinkedFrom)-1] != pageData.URL {
+				asset.LinkedFrom = append(asset.LinkedFrom, pageData.URL)
+			}
+			if link.MissingAlt && (len(asset.MissingAltOn) == 0 || asset.MissingAltOn[len(asset.MissingAltOn)-1] != pageData.URL) {
+				asset.MissingAltOn = append(asset.MissingAltOn, pageData.URL)
+			}
+		}
+	}
+
+	sorted := make([]*AssetResult, 0, len(assets))
+	for _, asset := range assets {
+		sorted = append(sorted, asset)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].URL < sorted[j].URL
+	})
+	return sorted
+}
+
+// checkAssets checks every asset of the crawled pages with its own pool of
+// assetConcurrency workers.
+func (c *Crawler) checkAssets(ctx context.Context) {
+	assets := collectAssets(c.Pages(), c.normalizer)
+
+	// each worker owns the entries of the assets it checks
+	checked := make([]bool, len(assets))
+	jobs := make(chan int)
+	wg := &sync.WaitGroup{}
+	for range c.assetConcurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				// robots.txt is read here, so a slow host holds up one worker
+				if !c.checkAllowed(ctx, assets[i].URL) {
+					continue
+				}
+				c.checkAsset(ctx, assets[i])
+				checked[i] = true
+			}
+		}()
+	}
+
+queue:
+	for i := range assets {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break queue
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, asset := range assets {
+		if checked[i] {
+			c.assets = append(c.assets, *asset)
+		}
+	}
+}
+
+// checkAsset sends a HEAD request for the asset and falls back to GET if
+// the server does not support HEAD or does not tell the size.
+func (c *Crawler) checkAsset(ctx context.Context, asset *AssetResult) {
+	start := time.Now()
+	err := c.fetchAsset(ctx, http.MethodHead, asset)
+	if err == nil && (asset.StatusCode == http.StatusMethodNotAllowed || asset.StatusCode == http.StatusNotImplemented ||
+		(asset.StatusCode < 400 && asset.Size < 0)) {
+		err = c.fetchAsset(ctx, http.MethodGet, asset)
+	}
+	asset.ResponseTime = time.Since(start)
+	if err != nil {
+		asset.Error = err.Error()
+	}
+
+	if asset.Broken() {
+		asset.Issues = append(asset.Issues, AssetBroken)
+	}
+	if c.maxAssetSize > 0 && asset.Size > c.maxAssetSize {
+		asset.Issues = append(asset.Issues, AssetOversized)
+	}
+	if len(asset.MissingAltOn) > 0 {
+		asset.Issues = append(asset.Issues, AssetMissingAlt)
+	}
+}
+
+func (c *Crawler) fetchAsset(ctx context.Context, method string, asset *AssetResult) error {
+	request, err := c.newRequest(ctx, method, asset.URL)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.send(c.client, request, c.delay)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	asset.StatusCode = response.StatusCode
+	asset.ContentType = response.Header.Get("Content-Type")
+	asset.Size = response.ContentLength
+	if method == http.MethodGet && asset.Size < 0 && response.StatusCode < 400 {
+		// no Content-Length, count the body; past the size limit the exact
+		// size does not matter
+		body := io.Reader(response.Body)
+		if c.maxAssetSize > 0 {
+			body = io.LimitReader(body, c.maxAssetSize+1)
+		}
+		size, err := io.Copy(io.Discard, body)
+		if err != nil {
+			return err
+		}
+		asset.Size = size
+	}
+	return nil
+}
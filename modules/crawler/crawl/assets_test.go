@@ -0,0 +1,88 @@
+package crawl
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCrawlAssetCheck(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private\nCrawl-delay: 0.001\n")
+	})
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><script src="/app.js"></script></head><body>
+			<img src="/logo.png" alt="Logo">
+			<img src="/big.png">
+			<img src="/missing.png" alt="">
+			<img src="/private/secret.png" alt="Secret">
+			<img src="http://127.0.0.1:1/dead.png" alt="Dead">
+		</body></html>`)
+	})
+	mux.HandleFunc("/logo.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		fmt.Fprint(w, "png")
+	})
+	mux.HandleFunc("/big.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		fmt.Fprint(w, strings.Repeat("x", 2000))
+	})
+	mux.HandleFunc("/app.js", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/javascript")
+		// flushing forces a chunked response without Content-Length
+		fmt.Fprint(w, "let a = 1;")
+		w.(http.Flusher).Flush()
+		fmt.Fprint(w, "let b = 2;")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	c := newTestCrawler(t, server.URL+"/", WithAssetCheck(2), WithMaxAssetSize(1000))
+
+	assets := make(map[string]AssetResult)
+	for _, asset := range c.Assets() {
+		assets[strings.TrimPrefix(asset.URL, server.URL)] = asset
+	}
+	if len(assets) != 5 {
+		t.Fatalf("expected 5 checked assets, got %+v", c.Assets())
+	}
+
+	tests := []struct {
+		path       string
+		kind       LinkKind
+		statusCode int
+		size       int64
+		issues     []string
+	}{
+		{"/app.js", LinkScript, 200, 20, nil},
+		{"/big.png", LinkImage, 200, 2000, []string{AssetOversized, AssetMissingAlt}},
+		{"/logo.png", LinkImage, 200, 3, nil},
+		{"/missing.png", LinkImage, 404, 19, []string{AssetBroken}},
+		// nothing listens there, robots.txt can't be read either
+		{"http://127.0.0.1:1/dead.png", LinkImage, 0, -1, []string{AssetBroken}},
+	}
+	for _, tc := range tests {
+		asset := assets[tc.path]
+		if asset.Kind != tc.kind || asset.StatusCode != tc.statusCode || asset.Size != tc.size {
+			t.Errorf("%s: expected %s %d with %d bytes, got %+v", tc.path, tc.kind, tc.statusCode, tc.size, asset)
+		}
+		if !reflect.DeepEqual(asset.Issues, tc.issues) {
+			t.Errorf("%s: expected issues %v, got %v", tc.path, tc.issues, asset.Issues)
+		}
+		if !reflect.DeepEqual(asset.LinkedFrom, []string{server.URL + "/"}) {
+			t.Errorf("%s: expected to be linked from the home page, got %v", tc.path, asset.LinkedFrom)
+		}
+	}
+	if !reflect.DeepEqual(c.Skipped(), []string{server.URL + "/private/secret.png"}) {
+		t.Errorf("expected robots.txt to block the private image, got %v", c.Skipped())
+	}
+}
@@ -0,0 +1,127 @@
+package crawl
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// How a page compares to the cached copy from the previous crawl.
+const (
+	ChangeNew       = "new"
+	ChangeModified  = "changed"
+	ChangeUnchanged = "unchanged"
+)
+
+// responseCache stores page responses on disk, one file per normalized
+// URL, so later crawls can revalidate them with conditional requests.
+type responseCache struct {
+	dir        string
+	normalizer *Normalizer
+}
+
+type cachedResponse struct {
+	URL       string      `json:"url"`
+	Header    http.Header `json:"header"`
+	Body      []byte      `json:"body"`
+	FetchedAt time.Time   `json:"fetched_at"`
+}
+
+func newResponseCache(dir string, normalizer *Normalizer) (*responseCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &responseCache{dir: dir, normalizer: normalizer}, nil
+}
+
+func (rc *responseCache) path(rawURL string) (string, error) {
+	normalizedURL, err := rc.normalizer.Normalize(rawURL)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(normalizedURL))
+	return filepath.Join(rc.dir, hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// get returns the cached response for rawURL, or nil if there is none.
+func (rc *responseCache) get(rawURL string) (*cachedResponse, error) {
+	path, err := rc.path(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cached cachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, err
+	}
+	return &cached, nil
+}
+
+func (rc *responseCache) put(rawURL string, cached *cachedResponse) error {
+	path, err := rc.path(rawURL)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// addValidators turns request into a conditional request for the cached
+// response.
+func (cached *cachedResponse) addValidators(request *http.Request) {
+	if etag := cached.Header.Get("ETag"); etag != "" {
+		request.Header.Set("If-None-Match", etag)
+	}
+	if lastModified := cached.Header.Get("Last-Modified"); lastModified != "" {
+		request.Header.Set("If-Modified-Since", lastModified)
+	}
+}
+
+// revalidated updates the cached headers with those of a 304 response.
+func (cached *cachedResponse) revalidated(header http.Header) {
+	for key, values := range header {
+		if key == "Content-Length" || key == "Set-Cookie" {
+			continue
+		}
+		cached.Header[key] = values
+	}
+	cached.FetchedAt = time.Now()
+}
+
+// change tells how body compares to the cached response.
+func (cached *cachedResponse) change(body []byte) string {
+	switch {
+	case cached == nil:
+		return ChangeNew
+	case bytes.Equal(cached.Body, body):
+		return ChangeUnchanged
+	default:
+		return ChangeModified
+	}
+}
+
+// newCachedResponse keeps the headers of response that matter for a
+// later crawl.
+func newCachedResponse(rawURL string, response *http.Response, body []byte) *cachedResponse {
+	header := response.Header.Clone()
+	header.Del("Set-Cookie")
+	header.Del("Content-Encoding") // the body is kept decoded
+	return &cachedResponse{URL: rawURL, Header: header, Body: body, FetchedAt: time.Now()}
+}
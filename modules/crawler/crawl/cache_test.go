@@ -0,0 +1,75 @@
+package crawl
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCrawlCacheRevalidation(t *testing.T) {
+	var version atomic.Int32
+	var notModified atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nCrawl-delay: 0.001\n")
+	})
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"home"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"home"`)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><h1>Home</h1><a href="/news">news</a><a href="/about">about</a></body></html>`)
+	})
+	mux.HandleFunc("/news", func(w http.ResponseWriter, r *http.Request) {
+		etag := fmt.Sprintf(`"news-%d"`, version.Load())
+		if r.Header.Get("If-None-Match") == etag {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><body><h1>News %d</h1></body></html>`, version.Load())
+	})
+	mux.HandleFunc("/about", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><h1>About</h1></body></html>`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	cacheDir := t.TempDir()
+
+	first := newTestCrawler(t, server.URL+"/", WithCache(cacheDir))
+	if len(first.Changes()) != 3 {
+		t.Errorf("expected 3 new pages on the first crawl, got %+v", first.Changes())
+	}
+
+	version.Store(1)
+	second := newTestCrawler(t, server.URL+"/", WithCache(cacheDir))
+
+	changes := make(map[string]string)
+	for _, result := range second.Fetches() {
+		changes[strings.TrimPrefix(result.URL, server.URL)] = result.Change
+	}
+	expected := map[string]string{"/": ChangeUnchanged, "/news": ChangeModified, "/about": ChangeUnchanged}
+	for path, change := range expected {
+		if changes[path] != change {
+			t.Errorf("%s: expected %q, got %q", path, change, changes[path])
+		}
+	}
+	if notModified.Load() != 1 {
+		t.Errorf("expected 1 not modified response, got %d", notModified.Load())
+	}
+	if len(second.Changes()) != 1 || second.Changes()[0].URL != server.URL+"/news" {
+		t.Errorf("expected only /news to have changed, got %+v", second.Changes())
+	}
+	if len(second.Pages()) != 3 {
+		t.Errorf("expected the cached home page to be parsed and its links followed, got %v", crawledPaths(second))
+	}
+}
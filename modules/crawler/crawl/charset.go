@@ -0,0 +1,32 @@
+package crawl
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+)
+
+// toUTF8 converts an HTML body to UTF-8. The charset comes from a byte
+// order mark, the Content-Type header or a <meta> tag in the first 1024
+// bytes, in that order, and is looked up in the WHATWG encoding standard
+// like browsers do, so Shift_JIS, GBK, EUC-KR, windows-1251 and the rest
+// are decoded too. Without any, a body that is valid UTF-8 is kept and
+// anything else is read as Windows-1252.
+func toUTF8(body []byte, contentType string) ([]byte, error) {
+	encoding, name, certain := charset.DetermineEncoding(body, contentType)
+	if !certain && name == "windows-1252" && utf8.Valid(body) {
+		// DetermineEncoding only sniffs the first 1024 bytes
+		return body, nil
+	}
+	decoded := body
+	if name != "utf-8" {
+		var err error
+		if decoded, err = encoding.NewDecoder().Bytes(body); err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", name, err)
+		}
+	}
+	// the decoders keep a byte order mark, now in UTF-8
+	return bytes.TrimPrefix(decoded, []byte("\xef\xbb\xbf")), nil
+}
@@ -0,0 +1,44 @@
+package crawl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToUTF8(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		expected    string
+	}{
+		{"utf-8", "<p>café</p>", "text/html; charset=utf-8", "<p>café</p>"},
+		{"no charset, valid utf-8", "<p>café</p>", "text/html", "<p>café</p>"},
+		{"no charset, not utf-8", "<p>caf\xe9 \x80</p>", "text/html", "<p>café €</p>"},
+		{"latin-1 header", "<p>caf\xe9</p>", "text/html; charset=ISO-8859-1", "<p>café</p>"},
+		{"windows-1252 header", "<p>\x93quoted\x94</p>", "text/html; charset=windows-1252", "<p>“quoted”</p>"},
+		{"meta charset", `<meta charset="iso-8859-1"><p>caf` + "\xe9</p>", "text/html", `<meta charset="iso-8859-1"><p>café</p>`},
+		{"meta http-equiv", `<meta http-equiv="Content-Type" content="text/html; charset=latin1">` + "\xe9", "text/html", `<meta http-equiv="Content-Type" content="text/html; charset=latin1">é`},
+		{"header wins over meta", `<meta charset="iso-8859-1">café`, "text/html; charset=utf-8", `<meta charset="iso-8859-1">café`},
+		{"utf-8 bom", "\xef\xbb\xbf<p>café</p>", "text/html; charset=iso-8859-1", "<p>café</p>"},
+		{"utf-16le bom", "\xff\xfe<\x00p\x00>\x00\xe9\x00", "text/html", "<p>é"},
+		{"utf-16be header", "\x00<\x00p\x00>\x00\xe9", "text/html; charset=utf-16be", "<p>é"},
+		{"shift_jis header", "<p>\x82\xa0</p>", "text/html; charset=shift_jis", "<p>あ</p>"},
+		{"shift_jis meta", `<meta charset="Shift_JIS"><p>` + "\x82\xa0</p>", "text/html", `<meta charset="Shift_JIS"><p>あ</p>`},
+		{"windows-1251 header", "<p>\xcf\xf0\xe8\xe2\xe5\xf2</p>", "text/html; charset=windows-1251", "<p>Привет</p>"},
+		{"gbk header", "<p>\xc4\xe3\xba\xc3</p>", "text/html; charset=gbk", "<p>你好</p>"},
+		{"euc-kr header", "<p>\xbe\xc8\xb3\xe7</p>", "text/html; charset=euc-kr", "<p>안녕</p>"},
+		{"utf-8 after the sniffed bytes", strings.Repeat(" ", 2048) + "café", "text/html", strings.Repeat(" ", 2048) + "café"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			decoded, err := toUTF8([]byte(tc.body), tc.contentType)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := string(decoded); actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package crawl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+const checkpointVersion = 1
+
+// checkpoint is the crawl state saved to disk: the frontier, the visited
+// set and everything collected so far.
+type checkpoint struct {
+	Version int                    `json:"version"`
+	BaseURL string                 `json:"base_url"`
+	Queue   []checkpointItem       `json:"queue"`
+	Seen    []string               `json:"seen"`
+	Pages   map[string]PageData    `json:"pages"`
+	Fetches map[string]FetchResult `json:"fetches"`
+	Skipped []string               `json:"skipped"`
+	Sitemap map[string]string      `json:"sitemap"`
+}
+
+type checkpointItem struct {
+	URL   string `json:"url"`
+	Depth int    `json:"depth"`
+}
+
+// startCheckpoints saves a checkpoint every checkpointInterval until the
+// returned function is called, which saves the final one.
+func (c *Crawler) startCheckpoints() (stop func() error) {
+	if c.checkpointPath == "" {
+		return func() error { return nil }
+	}
+
+	done := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	if c.checkpointInterval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(c.checkpointInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if err := c.saveCheckpoint(); err != nil {
+						c.logger.Warn("writing checkpoint", "path", c.checkpointPath, "error", err)
+					}
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	return func() error {
+		close(done)
+		wg.Wait()
+		if err := c.saveCheckpoint(); err != nil {
+			return fmt.Errorf("writing checkpoint: %w", err)
+		}
+		return nil
+	}
+}
+
+// saveCheckpoint writes the crawl state to the checkpoint file. The file
+// is replaced atomically, so a crash while writing keeps the previous one.
+func (c *Crawler) saveCheckpoint() error {
+	c.stateMu.Lock()
+	items, seen := c.frontier.snapshot()
+	c.mu.Lock()
+	state := checkpoint{
+		Version: checkpointVersion,
+		BaseURL: c.baseURL.String(),
+		Seen:    seen,
+		Pages:   maps.Clone(c.pages),
+		Fetches: maps.Clone(c.fetches),
+		Skipped: slices.Sorted(maps.Keys(c.skipped)),
+		Sitemap: maps.Clone(c.sitemap),
+	}
+	c.mu.Unlock()
+	c.stateMu.Unlock()
+
+	state.Queue = make([]checkpointItem, 0, len(items))
+	for _, item := range items {
+		state.Queue = append(state.Queue, checkpointItem{URL: item.url, Depth: item.depth})
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(c.checkpointPath, data)
+}
+
+// loadCheckpoint restores the state saved by saveCheckpoint.
+func (c *Crawler) loadCheckpoint() error {
+	data, err := os.ReadFile(c.checkpointPath)
+	if err != nil {
+		return err
+	}
+
+	var state checkpoint
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.Version != checkpointVersion {
+		return fmt.Errorf("unsupported checkpoint version %d", state.Version)
+	}
+	if state.BaseURL != c.baseURL.String() {
+		return fmt.Errorf("checkpoint is for %s, not %s", state.BaseURL, c.baseURL)
+	}
+
+	items := make([]crawlItem, 0, len(state.Queue))
+	for _, item := range state.Queue {
+		normalizedURL, err := c.normalizer.Normalize(item.URL)
+		if err != nil {
+			continue
+		}
+		items = append(items, crawlItem{url: item.URL, normalizedURL: normalizedURL, depth: item.Depth})
+	}
+
+	c.mu.Lock()
+	maps.Copy(c.pages, state.Pages)
+	maps.Copy(c.fetches, state.Fetches)
+	for _, skippedURL := range state.Skipped {
+		c.skipped[skippedURL] = struct{}{}
+	}
+	maps.Copy(c.sitemap, state.Sitemap)
+	crawled := len(c.pages)
+	c.mu.Unlock()
+
+	// every stored page counted towards maxPages
+	c.frontier.restore(items, state.Seen, crawled)
+	return nil
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	_, writeErr := file.Write(data)
+	closeErr := file.Close()
+	if err := errors.Join(writeErr, closeErr); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
@@ -0,0 +1,66 @@
+package crawl
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCrawlCheckpointResume(t *testing.T) {
+	server := newTestSite(t)
+	checkpointPath := filepath.Join(t.TempDir(), "crawl.checkpoint")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var pages atomic.Int32
+	onPage := func(PageData) {
+		if pages.Add(1) == 3 {
+			cancel()
+		}
+	}
+	first, err := New(server.URL+"/p/1", WithSitemap(false), WithConcurrency(2), WithMaxPages(10),
+		WithCheckpoint(checkpointPath, 0), WithPageCallback(onPage))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := first.Run(ctx); err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+	firstPages := first.Pages()
+
+	var newPages atomic.Int32
+	resumed := newTestCrawler(t, server.URL+"/p/1", WithConcurrency(2), WithMaxPages(10),
+		WithCheckpoint(checkpointPath, 0), WithResume(true),
+		WithPageCallback(func(PageData) { newPages.Add(1) }))
+
+	resumedPages := resumed.Pages()
+	if len(resumedPages) != 10 {
+		t.Errorf("expected 10 pages after resuming, got %d: %v", len(resumedPages), crawledPaths(resumed))
+	}
+	for key := range firstPages {
+		if _, found := resumedPages[key]; !found {
+			t.Errorf("expected %s from the checkpoint to be kept", key)
+		}
+	}
+	if int(newPages.Load()) != 10-len(firstPages) {
+		t.Errorf("expected %d newly crawled pages, got %d", 10-len(firstPages), newPages.Load())
+	}
+	if len(resumed.Skipped()) == 0 {
+		t.Errorf("expected skipped URLs to be kept")
+	}
+}
+
+func TestCrawlResumeOtherSite(t *testing.T) {
+	server := newTestSite(t)
+	checkpointPath := filepath.Join(t.TempDir(), "crawl.checkpoint")
+	newTestCrawler(t, server.URL+"/p/1", WithMaxPages(2), WithCheckpoint(checkpointPath, 0))
+
+	c, err := New(server.URL+"/p/2", WithSitemap(false), WithCheckpoint(checkpointPath, 0), WithResume(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Run(context.Background()); err == nil {
+		t.Errorf("expected an error for a checkpoint of another base URL, got nil")
+	}
+}
@@ -0,0 +1,148 @@
+package crawl
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"time"
+)
+
+const (
+	defaultConnectTimeout = 10 * time.Second
+	defaultHeaderTimeout  = 15 * time.Second
+	defaultTimeout        = 30 * time.Second
+	defaultMaxBodySize    = 10 << 20
+)
+
+// newHTTPClient builds the client used unless WithHTTPClient is given,
+// with the timeouts and the proxy of the options.
+func (c *Crawler) newHTTPClient() *http.Client {
+	proxy := http.ProxyFromEnvironment
+	if c.proxy != nil {
+		proxy = http.ProxyURL(c.proxy)
+	}
+	dialer := &net.Dialer{Timeout: c.connectTimeout, KeepAlive: 30 * time.Second}
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 proxy,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   c.connectTimeout,
+			ResponseHeaderTimeout: c.headerTimeout,
+			IdleConnTimeout:       90 * time.Second,
+			MaxIdleConns:          100,
+			MaxIdleConnsPerHost:   max(c.maxInFlight, http.DefaultMaxIdleConnsPerHost),
+			ForceAttemptHTTP2:     true,
+		},
+		Timeout: c.timeout,
+	}
+}
+
+// withCookieJar returns a copy of client with a cookie jar holding the
+// cookies for the base URL. Cookies the site sets are kept for later
+// requests, so a login session survives.
+func (c *Crawler) withCookieJar(client *http.Client) *http.Client {
+	jar, _ := cookiejar.New(nil) // never fails without options
+	jar.SetCookies(c.baseURL, c.cookies)
+	withJar := *client
+	withJar.Jar = jar
+	return &withJar
+}
+
+// addSiteCredentials adds the custom headers and basic auth to a request
+// for the crawled site. Other hosts never see them.
+func (c *Crawler) addSiteCredentials(request *http.Request) {
+	if !c.sameSite(request.URL) {
+		return
+	}
+	for name, values := range c.headers {
+		request.Header[name] = append([]string(nil), values...)
+	}
+	if c.basicAuth != nil {
+		password, _ := c.basicAuth.Password()
+		request.SetBasicAuth(c.basicAuth.Username(), password)
+	}
+}
+
+// withSiteCredentialsOnRedirect returns a copy of client that drops the
+// custom headers and basic auth when it follows a redirect off the site.
+// http.Client copies custom headers to any redirect target, even on another
+// host. The client's own redirect policy still applies.
+func (c *Crawler) withSiteCredentialsOnRedirect(client *http.Client) *http.Client {
+	checkRedirect := client.CheckRedirect
+	stripping := *client
+	stripping.CheckRedirect = func(request *http.Request, via []*http.Request) error {
+		if !c.sameSite(request.URL) {
+			for name := range c.headers {
+				delete(request.Header, name)
+			}
+			if c.basicAuth != nil {
+				request.Header.Del("Authorization")
+			}
+		}
+		if checkRedirect != nil {
+			return checkRedirect(request, via)
+		}
+		// the default policy of http.Client
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return &stripping
+}
+
+// readPageBody reads a page body up to maxSize bytes, 0 for no limit,
+// undoing its Content-Encoding and converting it to UTF-8. truncated
+// reports whether the body was longer. On a read error the part read so
+// far is returned with it.
+func readPageBody(header http.Header, body io.Reader, maxSize int64) (text string, truncated bool, err error) {
+	reader, err := contentDecoder(header.Get("Content-Encoding"), body)
+	if err != nil {
+		return "", false, err
+	}
+	if maxSize > 0 {
+		reader = io.LimitReader(reader, maxSize+1)
+	}
+	data, err := io.ReadAll(reader)
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		data, truncated = data[:maxSize], true
+	}
+	decoded, decodeErr := toUTF8(data, header.Get("Content-Type"))
+	if decodeErr != nil {
+		return "", truncated, decodeErr
+	}
+	return string(decoded), truncated, err
+}
+
+// contentDecoder undoes a gzip or deflate Content-Encoding. Deflate is
+// meant to be zlib-wrapped, but some servers send raw deflate data.
+func contentDecoder(encoding string, body io.Reader) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(body)
+	case "deflate":
+		buffered := bufio.NewReader(body)
+		header, err := buffered.Peek(2)
+		if err == io.EOF {
+			return buffered, nil // empty body
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			return zlib.NewReader(buffered)
+		}
+		return flate.NewReader(buffered), nil
+	}
+	return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+}
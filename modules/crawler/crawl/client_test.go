@@ -0,0 +1,217 @@
+package crawl
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadPageBody(t *testing.T) {
+	compress := func(newWriter func(io.Writer) io.WriteCloser, text string) string {
+		var buf bytes.Buffer
+		writer := newWriter(&buf)
+		io.WriteString(writer, text)
+		writer.Close()
+		return buf.String()
+	}
+	gzipped := compress(func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }, "<p>gzip</p>")
+	zlibbed := compress(func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }, "<p>deflate</p>")
+	rawDeflate := compress(func(w io.Writer) io.WriteCloser {
+		writer, _ := flate.NewWriter(w, flate.DefaultCompression)
+		return writer
+	}, "<p>raw deflate</p>")
+
+	tests := []struct {
+		name      string
+		encoding  string
+		body      string
+		maxSize   int64
+		expected  string
+		truncated bool
+	}{
+		{"plain", "", "<p>plain</p>", 0, "<p>plain</p>", false},
+		{"gzip", "gzip", gzipped, 0, "<p>gzip</p>", false},
+		{"zlib deflate", "deflate", zlibbed, 0, "<p>deflate</p>", false},
+		{"raw deflate", "deflate", rawDeflate, 0, "<p>raw deflate</p>", false},
+		{"empty deflate", "deflate", "", 0, "", false},
+		{"at the limit", "", "<p>plain</p>", 12, "<p>plain</p>", false},
+		{"over the limit", "", "<p>plain</p>", 5, "<p>pl", true},
+		{"limit after decompression", "gzip", gzipped, 6, "<p>gzi", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			header := http.Header{"Content-Type": {"text/html"}}
+			if tc.encoding != "" {
+				header.Set("Content-Encoding", tc.encoding)
+			}
+			actual, truncated, err := readPageBody(header, strings.NewReader(tc.body), tc.maxSize)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tc.expected || truncated != tc.truncated {
+				t.Errorf("expected %q truncated %v, got %q truncated %v", tc.expected, tc.truncated, actual, truncated)
+			}
+		})
+	}
+
+	header := http.Header{"Content-Encoding": {"br"}}
+	if _, _, err := readPageBody(header, strings.NewReader("x"), 0); err == nil {
+		t.Errorf("expected error for an unsupported encoding, got nil")
+	}
+}
+
+func TestCrawlFetcher(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nCrawl-delay: 0.001\n")
+	})
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "stage" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("X-Token") != "abc" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if cookie, err := r.Cookie("session"); err != nil || cookie.Value != "42" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Encoding", "gzip")
+		writer := gzip.NewWriter(w)
+		fmt.Fprint(writer, `<html><body><a href="/latin1">latin1</a><a href="/large">large</a><a href="/slow">slow</a></body></html>`)
+		writer.Close()
+	})
+	mux.HandleFunc("/latin1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+		fmt.Fprint(w, "<html><body><h1>Caf\xe9</h1></body></html>")
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><body><h1>Large</h1><p>%s</p></body></html>", strings.Repeat("x", 4096))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body><h1>Slow</h1></body></html>")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "crawl.warc.gz")
+	c := newTestCrawler(t, server.URL+"/",
+		WithWARC(path),
+		WithBasicAuth("stage", "secret"),
+		WithHeader("X-Token", "abc"),
+		WithCookies(&http.Cookie{Name: "session", Value: "42"}),
+		WithTimeouts(time.Second, 50*time.Millisecond, time.Second),
+		WithMaxBodySize(1024),
+		WithRetries(0),
+	)
+
+	fetches := make(map[string]FetchResult)
+	for _, result := range c.Fetches() {
+		fetches[strings.TrimPrefix(result.URL, server.URL)] = result
+	}
+	if result := fetches["/"]; result.StatusCode != http.StatusOK {
+		t.Fatalf("expected the site to accept the credentials, got %+v", result)
+	}
+	if result := fetches["/large"]; !result.Truncated || result.Error != "" {
+		t.Errorf("expected /large to be truncated, got %+v", result)
+	}
+	if result := fetches["/slow"]; result.Error == "" {
+		t.Errorf("expected /slow to time out, got %+v", result)
+	}
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	pages := c.Pages()
+	if h1 := pages[host+"/latin1"].H1; h1 != "Café" {
+		t.Errorf("expected H1 %q, got %q", "Café", h1)
+	}
+	if h1 := pages[host+"/large"].H1; h1 != "Large" {
+		t.Errorf("expected H1 %q from the truncated page, got %q", "Large", h1)
+	}
+	if _, found := pages[host+"/slow"]; found {
+		t.Errorf("expected /slow not to be crawled")
+	}
+
+	// the archive keeps the compressed and the cut bodies as received
+	if rebuilt := readWARCFiles(t, path).Pages(); !reflect.DeepEqual(rebuilt, pages) {
+		t.Errorf("expected the archived pages %v, got %v", pages, rebuilt)
+	}
+}
+
+func TestCredentialsStayOnSite(t *testing.T) {
+	c, err := New("https://stage.example.com/", WithBasicAuth("stage", "secret"), WithHeader("X-Token", "abc"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		{"https://stage.example.com/page", true},
+		{"https://cdn.example.net/app.js", false},
+	}
+	for _, tc := range tests {
+		request, err := c.newRequest(t.Context(), http.MethodGet, tc.url)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_, _, hasAuth := request.BasicAuth()
+		hasToken := request.Header.Get("X-Token") != ""
+		if hasAuth != tc.expected || hasToken != tc.expected {
+			t.Errorf("expected credentials %v for %s, got auth %v, token %v", tc.expected, tc.url, hasAuth, hasToken)
+		}
+	}
+}
+
+func TestCredentialsStayOnSiteAfterRedirect(t *testing.T) {
+	received := make(chan http.Header, 1)
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+	}))
+	defer other.Close()
+	// another host name for the same listener
+	otherURL := strings.Replace(other.URL, "127.0.0.1", "localhost", 1)
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, otherURL+"/logo.png", http.StatusFound)
+	}))
+	defer site.Close()
+
+	c, err := New(site.URL+"/", WithBasicAuth("stage", "secret"), WithHeader("X-Token", "abc"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	request, err := c.newRequest(t.Context(), http.MethodGet, site.URL+"/logo.png")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response, err := c.client.Do(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+
+	header := <-received
+	if header.Get("X-Token") != "" || header.Get("Authorization") != "" {
+		t.Errorf("expected no credentials on the other host, got %v", header)
+	}
+}
@@ -0,0 +1,593 @@
+// Package crawl crawls a single website breadth-first and extracts page data.
+package crawl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Crawler crawls the pages reachable from a base URL on the same host.
+// Configure it with options passed to New and start it with Run.
+type Crawler struct {
+	baseURL     *url.URL
+	client      *http.Client
+	pageClient  *http.Client // client without automatic redirects
+	userAgent   string
+	concurrency int
+	maxPages    int
+	maxDepth    int
+	delay       time.Duration // between requests to one host
+	maxInFlight int           // requests to one host at once, 0 for no limit
+	retries     int
+	useSitemap  bool
+	onPage      func(PageData)
+	logger      *slog.Logger
+	metrics     *Metrics // nil without WithMetrics
+	normalizer  *Normalizer
+	extractors  []Extractor
+
+	connectTimeout time.Duration
+	headerTimeout  time.Duration
+	timeout        time.Duration
+	proxy          *url.URL
+	maxBodySize    int64
+	headers        http.Header // sent to the crawled site only
+	cookies        []*http.Cookie
+	basicAuth      *url.Userinfo
+
+	assetConcurrency int // 0 disables the asset check
+	maxAssetSize     int64
+
+	externalConcurrency int // 0 disables the external link check
+
+	duplicateThreshold float64
+
+	allowSubdomains bool
+	sameDomain      bool
+	allowedHosts    []string
+	pathPrefix      string
+	include         []*regexp.Regexp
+	exclude         []*regexp.Regexp
+
+	cacheDir string
+	cache    *responseCache
+
+	warcPath string
+	warc     *warcArchive
+
+	checkpointPath     string
+	checkpointInterval time.Duration
+	resume             bool
+
+	mu       *sync.Mutex
+	stateMu  *sync.RWMutex // held for writing while a checkpoint is taken
+	pages    map[string]PageData
+	fetches  map[string]FetchResult
+	skipped  map[string]struct{}
+	sitemap  map[string]string
+	assets   []AssetResult
+	external map[string]ExternalLink // checked external links by normalized URL
+	frontier *frontier
+	robots   *robotsCache
+	limiter  *hostLimiter
+
+	started  time.Time     // when Run started, guarded by mu
+	finished time.Time     // when Run returned, guarded by mu
+	requests *atomic.Int64 // requests sent, see send
+	received *atomic.Int64 // response body bytes read
+}
+
+// New returns a Crawler for rawBaseURL. Without options it uses 5 workers,
+// stops after 100 pages, has no depth limit and reads the site's sitemaps.
+// Its client times out after 30s and reads at most 10 MiB of a page.
+func New(rawBaseURL string, opts ...Option) (*Crawler, error) {
+	baseURL, err := url.Parse(rawBaseURL)
+	if err != nil {
+		return nil, err
+	}
+	if !baseURL.IsAbs() || baseURL.Hostname() == "" {
+		return nil, errors.New("base URL must be absolute")
+	}
+
+	c := &Crawler{
+		baseURL:      baseURL,
+		userAgent:    DefaultUserAgent,
+		concurrency:  5,
+		maxPages:     100,
+		maxDepth:     -1,
+		delay:        defaultCrawlDelay,
+		maxInFlight:  defaultMaxInFlight,
+		retries:      defaultRetries,
+		useSitemap:   true,
+		normalizer:   DefaultNormalizer(),
+		logger:       slog.Default(),
+		extractors:   DefaultExtractors(),
+		maxAssetSize: defaultMaxAssetSize,
+		mu:           &sync.Mutex{},
+		stateMu:      &sync.RWMutex{},
+		pages:        make(map[string]PageData),
+		fetches:      make(map[string]FetchResult),
+		skipped:      make(map[string]struct{}),
+		sitemap:      make(map[string]string),
+		external:     make(map[string]ExternalLink),
+		requests:     &atomic.Int64{},
+		received:     &atomic.Int64{},
+
+		duplicateThreshold: defaultDuplicateThreshold,
+
+		connectTimeout: defaultConnectTimeout,
+		headerTimeout:  defaultHeaderTimeout,
+		timeout:        defaultTimeout,
+		maxBodySize:    defaultMaxBodySize,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.concurrency <= 0 {
+		return nil, errors.New("concurrency must be positive")
+	}
+	if c.maxPages <= 0 {
+		return nil, errors.New("max pages must be positive")
+	}
+	if c.delay < 0 {
+		return nil, errors.New("delay must not be negative")
+	}
+	if c.maxInFlight < 0 {
+		return nil, errors.New("max in-flight requests must not be negative")
+	}
+	if c.retries < 0 {
+		return nil, errors.New("retries must not be negative")
+	}
+	if c.connectTimeout < 0 || c.headerTimeout < 0 || c.timeout < 0 {
+		return nil, errors.New("timeouts must not be negative")
+	}
+	if c.maxBodySize < 0 {
+		return nil, errors.New("max body size must not be negative")
+	}
+	if c.assetConcurrency < 0 {
+		return nil, errors.New("asset concurrency must not be negative")
+	}
+	if c.externalConcurrency < 0 {
+		return nil, errors.New("external link concurrency must not be negative")
+	}
+	if c.duplicateThreshold <= 0 || c.duplicateThreshold > 1 {
+		return nil, errors.New("duplicate threshold must be between 0 and 1")
+	}
+	if c.resume && c.checkpointPath == "" {
+		return nil, errors.New("resuming needs a checkpoint file")
+	}
+	if c.cacheDir != "" {
+		if c.cache, err = newResponseCache(c.cacheDir, c.normalizer); err != nil {
+			return nil, fmt.Errorf("opening cache: %w", err)
+		}
+	}
+
+	if c.client == nil {
+		c.client = c.newHTTPClient()
+	}
+	if len(c.cookies) > 0 {
+		c.client = c.withCookieJar(c.client)
+	}
+	if len(c.headers) > 0 || c.basicAuth != nil {
+		c.client = c.withSiteCredentialsOnRedirect(c.client)
+	}
+	pageClient := *c.client
+	pageClient.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	c.pageClient = &pageClient
+
+	c.frontier = newFrontier(c.maxPages, c.maxDepth)
+	c.limiter = newHostLimiter(c.maxInFlight)
+	c.robots = newRobotsCache(c.getRobotsTxt, c.logger)
+	return c, nil
+}
+
+// BaseURL returns the URL the crawl starts from.
+func (c *Crawler) BaseURL() *url.URL {
+	return c.baseURL
+}
+
+// Run crawls until the frontier is drained, maxPages pages have been
+// crawled or ctx is done. A Crawler can only be run once. With a
+// checkpoint file the state is saved periodically and when Run returns,
+// also after ctx is cancelled. With a WARC file every page fetch is
+// archived; a fetch that can't be archived fails.
+func (c *Crawler) Run(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, c.frontier.close)
+	defer stop()
+
+	c.mu.Lock()
+	c.started = time.Now()
+	c.mu.Unlock()
+	defer c.metrics.track(c)()
+	defer func() {
+		c.mu.Lock()
+		c.finished = time.Now()
+		c.mu.Unlock()
+	}()
+
+	if c.resume {
+		if err := c.loadCheckpoint(); err != nil {
+			return fmt.Errorf("resuming crawl: %w", err)
+		}
+	} else {
+		// the base URL is always crawled, even if the filters exclude it
+		c.push(c.baseURL.String(), 0)
+		if c.useSitemap {
+			for _, seed := range c.loadSitemap(ctx) {
+				c.enqueue(seed, 0)
+			}
+		}
+	}
+
+	if c.warcPath != "" {
+		archive, err := c.openWARC()
+		if err != nil {
+			return fmt.Errorf("opening WARC file: %w", err)
+		}
+		c.warc = archive
+	}
+
+	stopCheckpoints := c.startCheckpoints()
+	wg := &sync.WaitGroup{}
+	for range c.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.worker(ctx)
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(stopCheckpoints(), c.warc.close()); err != nil {
+		return errors.Join(ctx.Err(), err)
+	}
+
+	if c.assetConcurrency > 0 && ctx.Err() == nil {
+		c.checkAssets(ctx)
+	}
+	if c.externalConcurrency > 0 && ctx.Err() == nil {
+		c.checkExternalLinks(ctx)
+	}
+	return ctx.Err()
+}
+
+// Assets returns the checked assets sorted by URL. It is empty unless the
+// asset check is enabled with WithAssetCheck.
+func (c *Crawler) Assets() []AssetResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.assets)
+}
+
+// Changes returns the fetches of pages that are new or changed since the
+// previous crawl, sorted by URL. It is empty unless the response cache is
+// enabled with WithCache.
+func (c *Crawler) Changes() []FetchResult {
+	var changes []FetchResult
+	for _, result := range c.Fetches() {
+		if result.Change == ChangeNew || result.Change == ChangeModified {
+			changes = append(changes, result)
+		}
+	}
+	return changes
+}
+
+// Duplicates returns the groups of pages with the same main text and with
+// near-duplicate main text, see WithDuplicateThreshold.
+func (c *Crawler) Duplicates() []DuplicateGroup {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return findDuplicates(c.pages, c.duplicateThreshold)
+}
+
+// Pages returns the crawled pages keyed by normalized URL.
+func (c *Crawler) Pages() map[string]PageData {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return maps.Clone(c.pages)
+}
+
+// Fetches returns every fetched URL with its status, sorted by URL.
+func (c *Crawler) Fetches() []FetchResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	results := make([]FetchResult, 0, len(c.fetches))
+	for _, result := range c.fetches {
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].URL < results[j].URL
+	})
+	return results
+}
+
+// BrokenLinks returns every 4xx/5xx or failed URL with the pages linking
+// to it.
+func (c *Crawler) BrokenLinks() []BrokenLink {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return brokenLinks(c.pages, c.fetches, c.normalizer)
+}
+
+// Redirects returns the fetches that were redirected at least once,
+// sorted by URL.
+func (c *Crawler) Redirects() []FetchResult {
+	var redirects []FetchResult
+	for _, result := range c.Fetches() {
+		if len(result.Redirects) > 0 {
+			redirects = append(redirects, result)
+		}
+	}
+	return redirects
+}
+
+// Skipped returns the URLs blocked by robots.txt in sorted order.
+func (c *Crawler) Skipped() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	urls := make([]string, 0, len(c.skipped))
+	for u := range c.skipped {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// SitemapCoverage returns sitemap pages that no crawled page links to, and
+// crawled pages that are linked but absent from the sitemap.
+func (c *Crawler) SitemapCoverage() (notLinked, notInSitemap []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return sitemapCoverage(c.pages, c.sitemap, c.normalizer)
+}
+
+// mergePage stores a page under key, which is its canonical identity. If
+// another URL already produced the same page the two are merged into one
+// entry, preferring the URL that is its own canonical, and isNew is false.
+func (c *Crawler) mergePage(key string, data PageData) (isNew bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	existing, found := c.pages[key]
+	if !found {
+		c.pages[key] = data
+		return true
+	}
+
+	primary, other := existing, data
+	if data.URL == data.Canonical && existing.URL != existing.Canonical {
+		primary, other = data, existing
+	}
+
+	var alternates []string
+	seen := map[string]struct{}{primary.URL: {}}
+	for _, alternate := range slices.Concat(primary.Alternates, other.Alternates, []string{other.URL}) {
+		if _, duplicate := seen[alternate]; duplicate {
+			continue
+		}
+		seen[alternate] = struct{}{}
+		alternates = append(alternates, alternate)
+	}
+	primary.Alternates = alternates
+	c.pages[key] = primary
+	return false
+}
+
+func (c *Crawler) setFetchResult(normalizedURL string, result FetchResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fetches[normalizedURL] = result
+}
+
+func (c *Crawler) addSkipped(rawURL string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.skipped[rawURL] = struct{}{}
+}
+
+// loadSitemap discovers and reads the sitemaps of the base URL's host and
+// returns the in-scope page URLs to seed the crawl with.
+func (c *Crawler) loadSitemap(ctx context.Context) []string {
+	robots, err := c.robots.get(ctx, c.baseURL)
+	if err != nil {
+		return nil
+	}
+	pageURLs := c.collectSitemapURLs(ctx, discoverSitemaps(c.baseURL, robots))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var seeds []string
+	for _, pageURL := range pageURLs {
+		parsedPageURL, err := url.Parse(pageURL)
+		if err != nil || !c.inScope(parsedPageURL) {
+			continue
+		}
+		normalizedPageURL, err := c.normalizer.Normalize(pageURL)
+		if err != nil {
+			continue
+		}
+		if _, found := c.sitemap[normalizedPageURL]; found {
+			continue
+		}
+		c.sitemap[normalizedPageURL] = pageURL
+		seeds = append(seeds, pageURL)
+	}
+	return seeds
+}
+
+// enqueue adds an in-scope URL to the frontier.
+func (c *Crawler) enqueue(rawURL string, depth int) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return
+	}
+
+	if !c.inScope(parsedURL) {
+		return
+	}
+
+	c.push(rawURL, depth)
+}
+
+func (c *Crawler) push(rawURL string, depth int) {
+	normalizedURL, err := c.normalizer.Normalize(rawURL)
+	if err != nil {
+		return
+	}
+
+	c.frontier.push(crawlItem{url: rawURL, normalizedURL: normalizedURL, depth: depth})
+}
+
+func (c *Crawler) worker(ctx context.Context) {
+	for {
+		item, ok := c.frontier.next()
+		if !ok {
+			return
+		}
+		crawled := c.crawlPage(ctx, item)
+		if crawled {
+			c.metrics.addPage()
+		}
+		if !crawled && ctx.Err() != nil {
+			// interrupted, keep the page for the checkpoint
+			c.frontier.requeue(item)
+		}
+		c.frontier.done(item, crawled)
+	}
+}
+
+// pageKey picks the identity of a fetched page: its same-site canonical
+// link, or else its final URL after redirects.
+func (c *Crawler) pageKey(pageData PageData) string {
+	key, _ := c.normalizer.Normalize(pageData.URL)
+	if pageData.Canonical == "" {
+		return key
+	}
+	parsedCanonical, err := url.Parse(pageData.Canonical)
+	if err != nil || !c.sameSite(parsedCanonical) {
+		return key
+	}
+	if normalizedCanonical, err := c.normalizer.Normalize(pageData.Canonical); err == nil {
+		return normalizedCanonical
+	}
+	return key
+}
+
+// crawlPage fetches one page, stores its data and queues its links. It
+// reports whether the page was crawled.
+func (c *Crawler) crawlPage(ctx context.Context, item crawlItem) bool {
+	parsedCurrentURL, err := url.Parse(item.url)
+	if err != nil {
+		return false
+	}
+
+	robots, err := c.robots.get(ctx, parsedCurrentURL)
+	if err != nil {
+		// cancelled before robots.txt was read, the URL isn't blocked
+		return false
+	}
+	if !robots.allowed(parsedCurrentURL.RequestURI()) {
+		c.logger.Debug("blocked by robots.txt", "url", item.url, "depth", item.depth)
+		c.addSkipped(item.url)
+		return false
+	}
+
+	result, rawHTML, err := c.getHTML(ctx, item.url)
+	if ctx.Err() != nil {
+		// cancelled, not a property of the page
+		return false
+	}
+	c.setFetchResult(item.normalizedURL, result)
+	c.metrics.observeFetch(result)
+	c.logFetch(item, result, err)
+	if err != nil || !result.IsHTML() {
+		return false
+	}
+
+	if len(result.Redirects) > 0 {
+		parsedFinalURL, err := url.Parse(result.FinalURL)
+		if err != nil || !c.sameSite(parsedFinalURL) {
+			// redirected off-site
+			return false
+		}
+		// the target is the same page, don't fetch it again
+		if normalizedFinalURL, err := c.normalizer.Normalize(result.FinalURL); err == nil {
+			c.frontier.markSeen(normalizedFinalURL)
+		}
+	}
+
+	pageData := c.extractPage(item.url, result, rawHTML)
+	return c.storePage(item, pageData)
+}
+
+// logFetch logs the outcome of a page fetch, failed ones as warnings.
+func (c *Crawler) logFetch(item crawlItem, result FetchResult, err error) {
+	attrs := []any{
+		"url", item.url,
+		"depth", item.depth,
+		"status", result.StatusCode,
+		"duration", result.ResponseTime,
+	}
+	if result.FinalURL != item.url {
+		attrs = append(attrs, "final_url", result.FinalURL)
+	}
+	if result.Retries > 0 {
+		attrs = append(attrs, "retries", result.Retries)
+	}
+	if err != nil {
+		c.logger.Warn("fetch failed", append(attrs, "error", err)...)
+		return
+	}
+	c.logger.Info("fetched page", append(attrs, "size", result.Size)...)
+}
+
+// extractPage extracts the data of a page fetched from rawURL. A page
+// reached through redirects lists rawURL as an alternate.
+func (c *Crawler) extractPage(rawURL string, result FetchResult, rawHTML string) PageData {
+	pageData := extractPageData(rawHTML, result.FinalURL, c.extractors)
+	if result.FinalURL != rawURL {
+		pageData.Alternates = []string{rawURL}
+	}
+	return pageData
+}
+
+// storePage saves a crawled page and queues its links. It reports whether
+// the page is new. Checkpoints wait for it, so they never hold a page
+// without its links.
+func (c *Crawler) storePage(item crawlItem, pageData PageData) bool {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+
+	if pageData.Canonical != "" {
+		// fetch the canonical version too so it becomes the primary URL
+		c.enqueue(pageData.Canonical, item.depth+1)
+	}
+	if isNew := c.mergePage(c.pageKey(pageData), pageData); !isNew {
+		return false
+	}
+	if c.onPage != nil {
+		c.onPage(pageData)
+	}
+
+	if !hasRobotsDirective(pageData.MetaRobots, "nofollow") {
+		for _, link := range pageData.Links {
+			if link.Kind == LinkAnchor && !link.Nofollow {
+				c.enqueue(link.URL, item.depth+1)
+			}
+		}
+	}
+	return true
+}
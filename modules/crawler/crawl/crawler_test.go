@@ -0,0 +1,306 @@
+package crawl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newTestSite serves a binary tree of pages: /p/n links to /p/2n and /p/2n+1.
+func newTestSite(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private\nCrawl-delay: 0.001\n")
+	})
+	mux.HandleFunc("/p/", func(w http.ResponseWriter, r *http.Request) {
+		n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/p/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><body><h1>Page %d</h1>
+			<a href="/p/%d">left</a>
+			<a href="/p/%d">right</a>
+			<a href="/private/%d">private</a>
+			<a href="/missing">missing</a>
+		</body></html>`, n, 2*n, 2*n+1, n)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestCrawler(t *testing.T, rawBaseURL string, opts ...Option) *Crawler {
+	t.Helper()
+	opts = append([]Option{WithSitemap(false)}, opts...)
+	c, err := New(rawBaseURL, opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return c
+}
+
+func crawledPaths(c *Crawler) []string {
+	var paths []string
+	for _, pageData := range c.Pages() {
+		parsedURL, _ := url.Parse(pageData.URL)
+		paths = append(paths, parsedURL.Path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestCrawlMaxPages_Race(t *testing.T) {
+	server := newTestSite(t)
+
+	const maxPages = 10
+	c := newTestCrawler(t, server.URL+"/p/1", WithConcurrency(5), WithMaxPages(maxPages))
+
+	if len(c.Pages()) != maxPages {
+		t.Errorf("expected exactly %d pages, got %d", maxPages, len(c.Pages()))
+	}
+	if len(c.Skipped()) == 0 {
+		t.Errorf("expected robots.txt to block /private URLs")
+	}
+}
+
+func TestCrawlBreadthFirst(t *testing.T) {
+	server := newTestSite(t)
+	c := newTestCrawler(t, server.URL+"/p/1", WithConcurrency(1), WithMaxPages(3))
+
+	expected := []string{"/p/1", "/p/2", "/p/3"}
+	actual := crawledPaths(c)
+	if strings.Join(actual, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestCrawlMaxDepth(t *testing.T) {
+	server := newTestSite(t)
+	c := newTestCrawler(t, server.URL+"/p/1", WithConcurrency(3), WithMaxDepth(2))
+
+	expected := []string{"/p/1", "/p/2", "/p/3", "/p/4", "/p/5", "/p/6", "/p/7"}
+	actual := crawledPaths(c)
+	if strings.Join(actual, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestCrawlCancel(t *testing.T) {
+	server := newTestSite(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var pages atomic.Int32
+	onPage := func(PageData) {
+		if pages.Add(1) == 3 {
+			cancel()
+		}
+	}
+
+	c, err := New(server.URL+"/p/1", WithSitemap(false), WithMaxPages(1000), WithPageCallback(onPage))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := c.Run(ctx); err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	if len(c.Pages()) >= 1000 {
+		t.Errorf("expected the crawl to stop early, got %d pages", len(c.Pages()))
+	}
+}
+
+func TestCrawlBrokenLinks(t *testing.T) {
+	server := newTestSite(t)
+
+	c := newTestCrawler(t, server.URL+"/p/1", WithConcurrency(2), WithMaxDepth(1))
+
+	brokenLinks := c.BrokenLinks()
+	if len(brokenLinks) != 1 {
+		t.Fatalf("expected 1 broken link, got %+v", brokenLinks)
+	}
+	if brokenLinks[0].URL != server.URL+"/missing" || brokenLinks[0].StatusCode != 404 {
+		t.Errorf("expected %s with status 404, got %+v", server.URL+"/missing", brokenLinks[0])
+	}
+	expectedLinkedFrom := []string{server.URL + "/p/1", server.URL + "/p/2", server.URL + "/p/3"}
+	if strings.Join(brokenLinks[0].LinkedFrom, ",") != strings.Join(expectedLinkedFrom, ",") {
+		t.Errorf("expected linked from %v, got %v", expectedLinkedFrom, brokenLinks[0].LinkedFrom)
+	}
+
+	fetches := c.Fetches()
+	if len(fetches) != 4 {
+		t.Errorf("expected 4 fetched URLs, got %d", len(fetches))
+	}
+	for _, result := range fetches {
+		if result.StatusCode == 200 && !result.IsHTML() {
+			t.Errorf("expected HTML content type for %s, got %q", result.URL, result.ContentType)
+		}
+	}
+}
+
+func TestCrawlProgress(t *testing.T) {
+	server := newTestSite(t)
+	c := newTestCrawler(t, server.URL+"/p/1", WithConcurrency(2), WithMaxDepth(1))
+
+	actual := c.Progress()
+	if actual.Elapsed <= 0 || actual.Bytes <= 0 {
+		t.Errorf("expected elapsed time and bytes, got %+v", actual)
+	}
+	actual.Elapsed, actual.Bytes = 0, 0
+	expected := Progress{Pages: 3, MaxPages: 100, Fetched: 4, Failed: 1, Skipped: 1, Requests: 4}
+	if actual != expected {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestCrawlLogs(t *testing.T) {
+	server := newTestSite(t)
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	newTestCrawler(t, server.URL+"/p/1", WithConcurrency(1), WithMaxDepth(1), WithLogger(logger))
+
+	type record struct {
+		Level    string  `json:"level"`
+		Msg      string  `json:"msg"`
+		URL      string  `json:"url"`
+		Depth    int     `json:"depth"`
+		Status   int     `json:"status"`
+		Duration float64 `json:"duration"`
+	}
+	records := make(map[string]record)
+	for line := range strings.Lines(buf.String()) {
+		var r record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		records[strings.TrimPrefix(r.URL, server.URL)] = r
+	}
+
+	tests := []struct {
+		path     string
+		expected record
+	}{
+		{"/p/1", record{Level: "INFO", Msg: "fetched page", Depth: 0, Status: 200}},
+		{"/p/2", record{Level: "INFO", Msg: "fetched page", Depth: 1, Status: 200}},
+		{"/missing", record{Level: "WARN", Msg: "fetch failed", Depth: 1, Status: 404}},
+		{"/private/1", record{Level: "DEBUG", Msg: "blocked by robots.txt", Depth: 1}},
+	}
+	for _, tc := range tests {
+		actual := records[tc.path]
+		if tc.expected.Msg != "blocked by robots.txt" && actual.Duration <= 0 {
+			t.Errorf("expected a duration for %s, got %+v", tc.path, actual)
+		}
+		actual.URL, actual.Duration = "", 0
+		if actual != tc.expected {
+			t.Errorf("expected %+v for %s, got %+v", tc.expected, tc.path, actual)
+		}
+	}
+}
+
+func TestCrawlRedirectsAndCanonical(t *testing.T) {
+	mux := http.NewServeMux()
+	page := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprintf(w, "<html><head>%s</head><body><h1>%s</h1></body></html>", body, r.URL.Path)
+		}
+	}
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private\nCrawl-delay: 0.001\n")
+	})
+	mux.HandleFunc("/{$}", page(`<a href="/old">old</a><a href="/new">new</a><a href="/loop">loop</a><a href="/print">print</a>
+		<a href="/moved">moved</a>`))
+	mux.Handle("/old", http.RedirectHandler("/older", http.StatusMovedPermanently))
+	mux.Handle("/older", http.RedirectHandler("/new", http.StatusFound))
+	mux.HandleFunc("/new", page(""))
+	mux.Handle("/loop", http.RedirectHandler("/loop2", http.StatusFound))
+	mux.Handle("/loop2", http.RedirectHandler("/loop", http.StatusFound))
+	mux.HandleFunc("/print", page(`<link rel="canonical" href="/article">`))
+	mux.HandleFunc("/article", page(`<link rel="canonical" href="/article">`))
+	mux.Handle("/moved", http.RedirectHandler("/private/page", http.StatusMovedPermanently))
+	var privateHits atomic.Int32
+	mux.HandleFunc("/private/", func(w http.ResponseWriter, r *http.Request) {
+		privateHits.Add(1)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	c := newTestCrawler(t, server.URL+"/", WithConcurrency(1))
+
+	pages := c.Pages()
+	if len(pages) != 3 {
+		t.Errorf("expected 3 pages after merging duplicates, got %d: %v", len(pages), crawledPaths(c))
+	}
+
+	newPage := pages[strings.TrimPrefix(server.URL, "http://")+"/new"]
+	if newPage.URL != server.URL+"/new" || strings.Join(newPage.Alternates, ",") != server.URL+"/old" {
+		t.Errorf("expected /new with alternate /old, got %+v", newPage)
+	}
+	article := pages[strings.TrimPrefix(server.URL, "http://")+"/article"]
+	if article.URL != server.URL+"/article" || article.Canonical != server.URL+"/article" ||
+		strings.Join(article.Alternates, ",") != server.URL+"/print" {
+		t.Errorf("expected /article with alternate /print, got %+v", article)
+	}
+
+	redirects := c.Redirects()
+	if len(redirects) != 3 {
+		t.Fatalf("expected 3 redirected fetches, got %+v", redirects)
+	}
+	loop, moved, old := redirects[0], redirects[1], redirects[2]
+	if !loop.RedirectLoop || !loop.Broken() {
+		t.Errorf("expected /loop to be a broken redirect loop, got %+v", loop)
+	}
+	if len(old.Redirects) != 2 || old.Redirects[0].StatusCode != 301 || old.FinalURL != server.URL+"/new" || old.RedirectLoop {
+		t.Errorf("expected a 2 hop chain from /old to /new, got %+v", old)
+	}
+	if len(moved.Redirects) != 1 || moved.FinalURL != server.URL+"/private/page" || moved.Broken() {
+		t.Errorf("expected /moved to stop at the blocked target, got %+v", moved)
+	}
+	if hits := privateHits.Load(); hits != 0 {
+		t.Errorf("expected the blocked target not to be requested, got %d requests", hits)
+	}
+	if !reflect.DeepEqual(c.Skipped(), []string{server.URL + "/private/page"}) {
+		t.Errorf("expected the redirect target to be skipped, got %v", c.Skipped())
+	}
+}
+
+func TestCrawlNofollow(t *testing.T) {
+	mux := http.NewServeMux()
+	page := func(head, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprintf(w, "<html><head>%s</head><body>%s</body></html>", head, body)
+		}
+	}
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nCrawl-delay: 0.001\n")
+	})
+	mux.HandleFunc("/{$}", page("", `<a href="/a">a</a><a href="/b" rel="nofollow">b</a><iframe src="/c"></iframe>`))
+	mux.HandleFunc("/a", page(`<meta name="robots" content="nofollow">`, `<a href="/d">d</a>`))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	c := newTestCrawler(t, server.URL+"/", WithConcurrency(1))
+
+	expected := []string{"/", "/a"}
+	if actual := crawledPaths(c); strings.Join(actual, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
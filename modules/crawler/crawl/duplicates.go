@@ -0,0 +1,170 @@
+package crawl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// defaultDuplicateThreshold is the SimHash similarity from which pages
+// count as near-duplicates: at most 3 of 64 bits differ.
+const defaultDuplicateThreshold = 0.95
+
+// simHashShingle is the number of consecutive words hashed together.
+const simHashShingle = 3
+
+// Kinds of duplicate groups.
+const (
+	DuplicateExact = "exact"
+	DuplicateNear  = "near"
+)
+
+// DuplicateGroup is a set of pages with the same or almost the same main
+// text, candidates for a shared canonical URL.
+type DuplicateGroup struct {
+	Kind string `json:"kind"`
+	// Similarity is the lowest SimHash similarity between two pages of the
+	// group, from 0 to 1.
+	Similarity float64  `json:"similarity"`
+	URLs       []string `json:"urls"` // sorted
+}
+
+// contentFingerprint hashes the main text of a page after lowercasing it
+// and dropping punctuation: SHA-256 for exact duplicates and a SimHash of
+// word shingles for near-duplicates. Both are empty for a page without
+// text.
+func contentFingerprint(text string) (contentHash, simHash string) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) == 0 {
+		return "", ""
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(words, " ")))
+
+	var weights [64]int
+	for i := range max(len(words)-simHashShingle+1, 1) {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:min(i+simHashShingle, len(words))], " ")))
+		shingle := h.Sum64()
+		for bit := range weights {
+			if shingle&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+	var fingerprint uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return hex.EncodeToString(sum[:]), fmt.Sprintf("%016x", fingerprint)
+}
+
+// simHashSimilarity is the share of equal bits of two SimHashes.
+func simHashSimilarity(a, b uint64) float64 {
+	return 1 - float64(bits.OnesCount64(a^b))/64
+}
+
+// findDuplicates groups the pages with the same content hash, and the
+// pages whose SimHashes are at least threshold similar. Near-duplicate
+// groups are the connected pages, so two of them may be less similar than
+// threshold; Similarity tells.
+func findDuplicates(pages map[string]PageData, threshold float64) []DuplicateGroup {
+	byHash := make(map[string][]string)
+	simHashes := make(map[string]uint64)
+	for _, pageData := range pages {
+		if pageData.ContentHash == "" {
+			continue
+		}
+		simHash, err := strconv.ParseUint(pageData.SimHash, 16, 64)
+		if err != nil {
+			continue
+		}
+		byHash[pageData.ContentHash] = append(byHash[pageData.ContentHash], pageData.URL)
+		simHashes[pageData.ContentHash] = simHash
+	}
+
+	var groups []DuplicateGroup
+	hashes := make([]string, 0, len(byHash))
+	for contentHash, urls := range byHash {
+		hashes = append(hashes, contentHash)
+		sort.Strings(urls)
+		if len(urls) > 1 {
+			groups = append(groups, DuplicateGroup{Kind: DuplicateExact, Similarity: 1, URLs: urls})
+		}
+	}
+	sort.Strings(hashes)
+
+	// union-find over the distinct contents
+	parent := make([]int, len(hashes))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	// SimHashes at most maxDiff bits apart agree on at least one of
+	// maxDiff+1 bands, so only the contents sharing a band are compared
+	maxDiff := int(math.Floor(64*(1-threshold) + 1e-9))
+	bands := maxDiff + 1
+	for band := range bands {
+		low, high := band*64/bands, (band+1)*64/bands
+		buckets := make(map[uint64][]int)
+		for i, contentHash := range hashes {
+			key := simHashes[contentHash] >> low & (1<<(high-low) - 1)
+			buckets[key] = append(buckets[key], i)
+		}
+		for _, bucket := range buckets {
+			for n, i := range bucket {
+				for _, j := range bucket[n+1:] {
+					if find(i) != find(j) && simHashSimilarity(simHashes[hashes[i]], simHashes[hashes[j]]) >= threshold {
+						parent[find(j)] = find(i)
+					}
+				}
+			}
+		}
+	}
+
+	components := make(map[int][]int)
+	for i := range hashes {
+		components[find(i)] = append(components[find(i)], i)
+	}
+	for _, members := range components {
+		if len(members) < 2 {
+			continue
+		}
+		group := DuplicateGroup{Kind: DuplicateNear, Similarity: 1}
+		for n, i := range members {
+			group.URLs = append(group.URLs, byHash[hashes[i]]...)
+			for _, j := range members[n+1:] {
+				group.Similarity = min(group.Similarity, simHashSimilarity(simHashes[hashes[i]], simHashes[hashes[j]]))
+			}
+		}
+		sort.Strings(group.URLs)
+		groups = append(groups, group)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].URLs[0] != groups[j].URLs[0] {
+			return groups[i].URLs[0] < groups[j].URLs[0]
+		}
+		return groups[i].Kind < groups[j].Kind
+	})
+	return groups
+}
@@ -0,0 +1,118 @@
+package crawl
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testArticle returns n words of text, with the word at index changed to
+// replacement if index is not negative.
+func testArticle(n, index int, replacement string) string {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = fmt.Sprintf("word%d", i*7%31+i/31)
+	}
+	if index >= 0 {
+		words[index] = replacement
+	}
+	return strings.Join(words, " ")
+}
+
+func TestContentFingerprint(t *testing.T) {
+	hash, simHash := contentFingerprint("Hello, World! Hello  world.")
+	otherHash, otherSimHash := contentFingerprint("hello world hello WORLD")
+	if hash != otherHash || simHash != otherSimHash {
+		t.Errorf("expected case and punctuation to be ignored, got %s %s and %s %s", hash, simHash, otherHash, otherSimHash)
+	}
+	if len(hash) != 64 || len(simHash) != 16 {
+		t.Errorf("expected a SHA-256 and a 64-bit SimHash, got %q and %q", hash, simHash)
+	}
+	if hash, simHash := contentFingerprint(" ... "); hash != "" || simHash != "" {
+		t.Errorf("expected no fingerprint without words, got %q and %q", hash, simHash)
+	}
+
+	similarity := func(a, b string) float64 {
+		_, simA := contentFingerprint(a)
+		_, simB := contentFingerprint(b)
+		x, _ := strconv.ParseUint(simA, 16, 64)
+		y, _ := strconv.ParseUint(simB, 16, 64)
+		return simHashSimilarity(x, y)
+	}
+	if s := similarity(testArticle(300, -1, ""), testArticle(300, 150, "changed")); s < defaultDuplicateThreshold {
+		t.Errorf("expected a one word edit to be a near-duplicate, got similarity %v", s)
+	}
+	if s := similarity(testArticle(300, -1, ""), "An entirely different page about something else."); s >= defaultDuplicateThreshold {
+		t.Errorf("expected different pages not to be near-duplicates, got similarity %v", s)
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	page := func(pageURL, text string) PageData {
+		pageData := PageData{URL: pageURL}
+		pageData.ContentHash, pageData.SimHash = contentFingerprint(text)
+		return pageData
+	}
+	pages := map[string]PageData{}
+	for _, pageData := range []PageData{
+		page("https://blog.test.dev/post", testArticle(300, -1, "")),
+		page("https://blog.test.dev/post?print=1", testArticle(300, -1, "")),
+		page("https://blog.test.dev/post/amp", testArticle(300, 150, "changed")),
+		page("https://blog.test.dev/about", "About this blog, its authors and its history."),
+		page("https://blog.test.dev/empty", ""),
+	} {
+		pages[pageData.URL] = pageData
+	}
+
+	groups := findDuplicates(pages, defaultDuplicateThreshold)
+	if len(groups) != 2 {
+		t.Fatalf("expected an exact and a near-duplicate group, got %+v", groups)
+	}
+	exact, near := groups[0], groups[1]
+	if exact.Kind != DuplicateExact || exact.Similarity != 1 ||
+		!reflect.DeepEqual(exact.URLs, []string{"https://blog.test.dev/post", "https://blog.test.dev/post?print=1"}) {
+		t.Errorf("unexpected exact group %+v", exact)
+	}
+	if near.Kind != DuplicateNear || near.Similarity < defaultDuplicateThreshold ||
+		!reflect.DeepEqual(near.URLs, []string{"https://blog.test.dev/post", "https://blog.test.dev/post/amp", "https://blog.test.dev/post?print=1"}) {
+		t.Errorf("unexpected near-duplicate group %+v", near)
+	}
+}
+
+func TestFindDuplicatesMatchesPairwise(t *testing.T) {
+	// pairs of SimHashes up to 12 random bits apart
+	random := rand.New(rand.NewPCG(1, 2))
+	pages := map[string]PageData{}
+	for pair := range 200 {
+		simHash := random.Uint64()
+		for member := range 2 {
+			pageURL := fmt.Sprintf("https://blog.test.dev/%d/%d", pair, member)
+			pages[pageURL] = PageData{URL: pageURL, ContentHash: pageURL, SimHash: fmt.Sprintf("%016x", simHash)}
+			for range random.IntN(13) {
+				simHash ^= 1 << random.IntN(64)
+			}
+		}
+	}
+
+	for _, threshold := range []float64{1, 0.97, defaultDuplicateThreshold, 0.9, 0.5, 0} {
+		groups := findDuplicates(pages, threshold)
+		groupOf := make(map[string]int)
+		for i, group := range groups {
+			for _, pageURL := range group.URLs {
+				groupOf[pageURL] = i + 1
+			}
+		}
+		for _, a := range pages {
+			for _, b := range pages {
+				x, _ := strconv.ParseUint(a.SimHash, 16, 64)
+				y, _ := strconv.ParseUint(b.SimHash, 16, 64)
+				if a.URL != b.URL && simHashSimilarity(x, y) >= threshold && (groupOf[a.URL] == 0 || groupOf[a.URL] != groupOf[b.URL]) {
+					t.Fatalf("threshold %v: expected %s and %s in a group, got %+v", threshold, a.URL, b.URL, groups)
+				}
+			}
+		}
+	}
+}
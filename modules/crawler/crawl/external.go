@@ -0,0 +1,165 @@
+package crawl
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ExternalLink is a link of the crawled pages that is out of the crawl's
+// scope. It is never crawled; its status is only known if the external
+// link check is enabled with WithExternalLinkCheck.
+type ExternalLink struct {
+	URL          string        `json:"url"`
+	Checked      bool          `json:"checked"`
+	StatusCode   int           `json:"status_code,omitempty"`
+	ResponseTime time.Duration `json:"response_time_ns,omitempty"`
+	Error        string        `json:"error,omitempty"`
+	LinkedFrom   []string      `json:"linked_from"`
+}
+
+// Broken reports whether the checked link failed with a 4xx/5xx status or
+// could not be fetched at all.
+func (l ExternalLink) Broken() bool {
+	return l.Checked && (l.StatusCode >= 400 || l.Error != "")
+}
+
+// ExternalLinks returns the out-of-scope anchors of the crawled pages with
+// the pages linking to them, sorted by URL.
+func (c *Crawler) ExternalLinks() []ExternalLink {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	links := c.collectExternalLinks()
+	result := make([]ExternalLink, 0, len(links))
+	for normalizedURL, link := range links {
+		if checked, found := c.external[normalizedURL]; found {
+			link.Checked = true
+			link.StatusCode = checked.StatusCode
+			link.ResponseTime = checked.ResponseTime
+			link.Error = checked.Error
+		}
+		result = append(result, *link)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].URL < result[j].URL
+	})
+	return result
+}
+
+// collectExternalLinks lists the anchors of the pages that are out of
+// scope by normalized URL. Links to fetched URLs don't count, the base URL
+// is crawled even if the filters exclude it. c.mu must be held.
+func (c *Crawler) collectExternalLinks() map[string]*ExternalLink {
+	sortedPages := make([]PageData, 0, len(c.pages))
+	for _, pageData := range c.pages {
+		sortedPages = append(sortedPages, pageData)
+	}
+	sort.Slice(sortedPages, func(i, j int) bool {
+		return sortedPages[i].URL < sortedPages[j].URL
+	})
+
+	links := make(map[string]*ExternalLink)
+	for _, pageData := range sortedPages {
+		for _, link := range pageData.Links {
+			if link.Kind != LinkAnchor {
+				continue
+			}
+			parsedURL, err := url.Parse(link.URL)
+			if err != nil || c.inScope(parsedURL) {
+				continue
+			}
+			normalizedURL, err := c.normalizer.Normalize(link.URL)
+			if err != nil {
+				continue
+			}
+			if _, fetched := c.fetches[normalizedURL]; fetched {
+				continue
+			}
+			external, found := links[normalizedURL]
+			if !found {
+				external = &ExternalLink{URL: link.URL}
+				links[normalizedURL] = external
+			}
+			if len(external.LinkedFrom) == 0 || external.LinkedFrom[len(external.LinkedFrom)-1] != pageData.URL {
+				external.LinkedFrom = append(external.LinkedFrom, pageData.URL)
+			}
+		}
+	}
+	return links
+}
+
+// checkExternalLinks requests every external link of the crawled pages
+// once, with its own pool of externalConcurrency workers.
+func (c *Crawler) checkExternalLinks(ctx context.Context) {
+	c.mu.Lock()
+	links := c.collectExternalLinks()
+	c.mu.Unlock()
+
+	type job struct {
+		normalizedURL string
+		link          *ExternalLink
+	}
+	jobs := make(chan job)
+	wg := &sync.WaitGroup{}
+	for range c.externalConcurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				// robots.txt is read here, so a slow host holds up one worker
+				if !c.checkAllowed(ctx, j.link.URL) {
+					continue
+				}
+				c.checkExternalLink(ctx, j.link)
+				if ctx.Err() != nil {
+					continue
+				}
+				c.mu.Lock()
+				c.external[j.normalizedURL] = *j.link
+				c.mu.Unlock()
+			}
+		}()
+	}
+
+queue:
+	for normalizedURL, link := range links {
+		select {
+		case jobs <- job{normalizedURL, link}:
+		case <-ctx.Done():
+			break queue
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// checkExternalLink sends a HEAD request for the link and falls back to
+// GET if the server does not support HEAD. Redirects are followed.
+func (c *Crawler) checkExternalLink(ctx context.Context, link *ExternalLink) {
+	start := time.Now()
+	status, err := c.linkStatus(ctx, http.MethodHead, link.URL)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = c.linkStatus(ctx, http.MethodGet, link.URL)
+	}
+	link.ResponseTime = time.Since(start)
+	link.StatusCode = status
+	if err != nil {
+		link.Error = err.Error()
+	}
+}
+
+func (c *Crawler) linkStatus(ctx context.Context, method, rawURL string) (int, error) {
+	request, err := c.newRequest(ctx, method, rawURL)
+	if err != nil {
+		return 0, err
+	}
+	response, err := c.send(c.client, request, c.delay)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	return response.StatusCode, nil
+}
@@ -0,0 +1,113 @@
+package crawl
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Page is a fetched HTML page, parsed once and shared by all extractors.
+type Page struct {
+	URL      string
+	Document *goquery.Document
+}
+
+// Extractor fills in part of PageData from a parsed page. Extractors run in
+// order on the same document, so they must not modify it.
+type Extractor interface {
+	Extract(page *Page, data *PageData)
+}
+
+// ExtractorFunc adapts a function to the Extractor interface.
+type ExtractorFunc func(page *Page, data *PageData)
+
+// Extract calls f(page, data).
+func (f ExtractorFunc) Extract(page *Page, data *PageData) {
+	f(page, data)
+}
+
+// DefaultExtractors returns the extractors for the built-in PageData
+// fields. Custom extractors are run after them.
+func DefaultExtractors() []Extractor {
+	return []Extractor{
+		ExtractorFunc(func(page *Page, data *PageData) {
+			data.Title = getTitleFromHTML(page.Document)
+			data.H1 = getH1FromHTML(page.Document)
+			data.FirstParagraph = getFirstParagraphFromHTML(page.Document)
+			data.Headings = getHeadingsFromHTML(page.Document)
+			data.WordCount = getWordCountFromHTML(page.Document)
+			data.ContentHash, data.SimHash = contentFingerprint(getMainTextFromHTML(page.Document))
+		}),
+		ExtractorFunc(func(page *Page, data *PageData) {
+			meta := getMetaFromHTML(page.Document)
+			data.MetaDescription = meta["description"]
+			data.MetaRobots = meta["robots"]
+			data.OpenGraph = metaWithPrefix(meta, "og:")
+			data.TwitterCard = metaWithPrefix(meta, "twitter:")
+		}),
+		ExtractorFunc(func(page *Page, data *PageData) {
+			base := getBaseURL(page.Document, page.URL)
+			data.Links = getLinksFromHTML(page.Document, base)
+			data.OutgoingLinks = linkURLs(data.Links, LinkAnchor)
+			data.ImageURLs = linkURLs(data.Links, LinkImage)
+			data.Canonical = getCanonicalFromHTML(page.Document, base)
+			data.Hreflang = getHreflangFromHTML(page.Document, base)
+		}),
+		ExtractorFunc(func(page *Page, data *PageData) {
+			data.JSONLD = getJSONLDFromHTML(page.Document)
+		}),
+	}
+}
+
+// SelectorExtractor stores the text, or the attribute Attr, of the elements
+// matching a CSS selector in PageData.Extra under Name.
+type SelectorExtractor struct {
+	Name     string
+	Selector string
+	// Attr is the attribute to read; empty means the element text.
+	Attr string
+	// All keeps every match instead of only the first one.
+	All bool
+}
+
+// Extract implements Extractor.
+func (e SelectorExtractor) Extract(page *Page, data *PageData) {
+	selection := page.Document.Find(e.Selector)
+	if !e.All {
+		selection = selection.First()
+	}
+
+	var values []string
+	selection.Each(func(_ int, item *goquery.Selection) {
+		if e.Attr == "" {
+			values = append(values, strings.Join(strings.Fields(item.Text()), " "))
+			return
+		}
+		if value, exist := item.Attr(e.Attr); exist {
+			values = append(values, strings.TrimSpace(value))
+		}
+	})
+	if len(values) == 0 {
+		return
+	}
+
+	if data.Extra == nil {
+		data.Extra = make(map[string][]string)
+	}
+	data.Extra[e.Name] = append(data.Extra[e.Name], values...)
+}
+
+// extractPageData parses htmlBody once and runs the extractors on it.
+func extractPageData(htmlBody, pageURL string, extractors []Extractor) PageData {
+	data := PageData{URL: pageURL}
+	doc, err := parseHTML(htmlBody)
+	if err != nil {
+		return data
+	}
+
+	page := &Page{URL: pageURL, Document: doc}
+	for _, extractor := range extractors {
+		extractor.Extract(page, &data)
+	}
+	return data
+}
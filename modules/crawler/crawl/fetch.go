@@ -0,0 +1,315 @@
+package crawl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const maxRedirects = 10
+
+// Redirect is one hop of a redirect chain: URL answered with StatusCode.
+type Redirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+}
+
+// FetchResult records the outcome of one request, successful or not.
+type FetchResult struct {
+	URL          string        `json:"url"`
+	FinalURL     string        `json:"final_url"`
+	StatusCode   int           `json:"status_code"`
+	ContentType  string        `json:"content_type"`
+	ResponseTime time.Duration `json:"response_time_ns"`
+	Error        string        `json:"error,omitempty"`
+	Redirects    []Redirect    `json:"redirects,omitempty"`
+	RedirectLoop bool          `json:"redirect_loop,omitempty"`
+	Retries      int           `json:"retries,omitempty"`   // failed attempts that were tried again
+	Truncated    bool          `json:"truncated,omitempty"` // the body was cut at the max body size
+	Size         int64         `json:"size,omitempty"`      // bytes of the decoded HTML page
+	// Change compares the page with the previous crawl when the response
+	// cache is enabled: ChangeNew, ChangeModified or ChangeUnchanged.
+	Change string `json:"change,omitempty"`
+}
+
+// Broken reports whether the URL failed with a 4xx/5xx status or could not
+// be fetched at all, e.g. because of a timeout.
+func (r FetchResult) Broken() bool {
+	return r.StatusCode >= 400 || r.Error != ""
+}
+
+// IsHTML reports whether the response was an HTML page.
+func (r FetchResult) IsHTML() bool {
+	return strings.Contains(r.ContentType, "text/html")
+}
+
+// BrokenLink is a broken URL together with the crawled pages linking to it.
+type BrokenLink struct {
+	FetchResult
+	LinkedFrom []string `json:"linked_from"`
+}
+
+// newRequest builds a request carrying the crawler's User-Agent.
+func (c *Crawler) newRequest(ctx context.Context, method, rawURL string) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Add("User-Agent", c.userAgent) // Set a custom User-Agent
+	c.addSiteCredentials(request)
+	return request, nil
+}
+
+// getHTML fetches a page. The body is only returned for successful HTML
+// responses; the result is filled in either way.
+func (c *Crawler) getHTML(ctx context.Context, rawURL string) (FetchResult, string, error) {
+	result := FetchResult{URL: rawURL, FinalURL: rawURL}
+	start := time.Now()
+	body, err := c.doGetHTML(ctx, &result)
+	result.ResponseTime = time.Since(start)
+	result.Size = int64(len(body))
+	if err != nil {
+		result.Error = err.Error()
+	}
+	// an interrupted fetch is not part of the crawl, it is redone on resume
+	if ctx.Err() == nil {
+		if archiveErr := c.warc.writeMetadata(result); archiveErr != nil && err == nil {
+			err = fmt.Errorf("archiving: %w", archiveErr)
+			result.Error = err.Error()
+		}
+	}
+	if err != nil {
+		return result, "", err
+	}
+	return result, body, nil
+}
+
+// doGetHTML follows redirects itself so that every hop is recorded. A
+// redirect to a URL robots.txt blocks ends the chain and the target is
+// skipped.
+func (c *Crawler) doGetHTML(ctx context.Context, result *FetchResult) (string, error) {
+	visited := map[string]struct{}{result.URL: {}}
+	currentURL := result.URL
+	for {
+		nextURL, body, err := c.getHTMLRetrying(ctx, currentURL, result)
+		if err != nil || nextURL == "" {
+			return body, err
+		}
+
+		result.Redirects = append(result.Redirects, Redirect{URL: currentURL, StatusCode: result.StatusCode})
+		if _, found := visited[nextURL]; found {
+			result.RedirectLoop = true
+			result.FinalURL = nextURL
+			return "", errors.New("redirect loop")
+		}
+		if len(result.Redirects) >= maxRedirects {
+			result.FinalURL = nextURL
+			return "", fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		parsedNextURL, err := url.Parse(nextURL)
+		if err != nil {
+			return "", err
+		}
+		robots, err := c.robots.get(ctx, parsedNextURL)
+		if err != nil {
+			return "", err
+		}
+		if !robots.allowed(parsedNextURL.RequestURI()) {
+			c.logger.Debug("redirect blocked by robots.txt", "url", result.URL, "target", nextURL)
+			c.addSkipped(nextURL)
+			result.FinalURL = nextURL
+			return "", nil
+		}
+		visited[nextURL] = struct{}{}
+		currentURL = nextURL
+	}
+}
+
+// getHTMLRetrying retries getHTMLOnce after network errors, 429 and
+// temporary server errors, with exponential backoff and jitter.
+func (c *Crawler) getHTMLRetrying(ctx context.Context, rawURL string, result *FetchResult) (string, string, error) {
+	for attempt := 0; ; attempt++ {
+		result.StatusCode = 0
+		nextURL, body, err := c.getHTMLOnce(ctx, rawURL, result)
+		if err == nil || attempt >= c.retries || ctx.Err() != nil || !retryable(result.StatusCode) {
+			return nextURL, body, err
+		}
+
+		timer := time.NewTimer(retryBackoff(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nextURL, body, err
+		}
+		result.Retries++
+	}
+}
+
+// getHTMLOnce does a single request. For a redirect it returns the
+// resolved Location instead of a body. With the response cache enabled it
+// asks for the page only if it changed since the cached copy.
+func (c *Crawler) getHTMLOnce(ctx context.Context, rawURL string, result *FetchResult) (string, string, error) {
+	request, err := c.newRequest(ctx, http.MethodGet, rawURL)
+	if err != nil {
+		return "", "", err
+	}
+	// decoded by readPageBody, so the archive keeps the body as sent
+	request.Header.Set("Accept-Encoding", "gzip, deflate")
+
+	var cached *cachedResponse
+	if c.cache != nil {
+		cached, err = c.cache.get(rawURL)
+		if err != nil {
+			c.logger.Warn("reading cache", "url", rawURL, "error", err)
+		}
+		if cached != nil {
+			cached.addValidators(request)
+		}
+	}
+
+	result.FinalURL = rawURL
+	robots, err := c.robots.get(ctx, request.URL)
+	if err != nil {
+		return "", "", err
+	}
+	response, err := c.send(c.pageClient, request, robots.delay(c.delay))
+	if err != nil {
+		if archiveErr := c.warc.writeExchange(request, nil, nil, ""); archiveErr != nil {
+			c.logger.Warn("archiving failed request", "url", rawURL, "error", archiveErr)
+		}
+		return "", "", err
+	}
+	defer response.Body.Close()
+
+	if c.warc != nil {
+		// archive the whole response, also the bodies the crawl does not
+		// read, up to the max body size
+		body, readErr := readLimited(response.Body, c.maxBodySize)
+		truncated := ""
+		switch {
+		case readErr != nil:
+			truncated = "disconnect"
+		case c.maxBodySize > 0 && int64(len(body)) > c.maxBodySize:
+			body, truncated = body[:c.maxBodySize], "length"
+			result.Truncated = true
+		}
+		if err := c.warc.writeExchange(request, response, body, truncated); err != nil {
+			return "", "", fmt.Errorf("archiving: %w", err)
+		}
+		if readErr != nil {
+			return "", "", readErr
+		}
+		response.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	result.StatusCode = response.StatusCode
+
+	switch response.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		location, err := response.Location()
+		if err != nil {
+			return "", "", fmt.Errorf("redirect without location: %w", err)
+		}
+		return location.String(), "", nil
+	case http.StatusNotModified:
+		if cached == nil {
+			return "", "", errors.New("not modified without a cached copy")
+		}
+		cached.revalidated(response.Header)
+		if err := c.cache.put(rawURL, cached); err != nil {
+			c.logger.Warn("writing cache", "url", rawURL, "error", err)
+		}
+		result.ContentType = cached.Header.Get("Content-Type")
+		result.Change = ChangeUnchanged
+		if !result.IsHTML() {
+			return "", "", nil
+		}
+		return "", string(cached.Body), nil
+	}
+
+	result.ContentType = response.Header.Get("Content-Type")
+
+	if response.StatusCode >= 400 {
+		return "", "", fmt.Errorf("received status code %d", response.StatusCode)
+	}
+
+	if !result.IsHTML() {
+		// not an error, there is just nothing to parse
+		return "", "", nil
+	}
+
+	body, truncated, err := readPageBody(response.Header, response.Body, c.maxBodySize)
+	if err != nil && !(result.Truncated && errors.Is(err, io.ErrUnexpectedEOF)) {
+		// a compressed body cut short for the archive ends early, that is
+		// no reason to drop what was decoded
+		return "", "", err
+	}
+	result.Truncated = result.Truncated || truncated
+
+	if c.cache != nil && response.StatusCode == http.StatusOK {
+		result.Change = cached.change([]byte(body))
+		if err := c.cache.put(rawURL, newCachedResponse(rawURL, response, []byte(body))); err != nil {
+			c.logger.Warn("writing cache", "url", rawURL, "error", err)
+		}
+	}
+	return "", body, nil
+}
+
+// readLimited reads r up to one byte past maxSize, 0 for no limit, so the
+// caller can tell whether there was more.
+func readLimited(r io.Reader, maxSize int64) ([]byte, error) {
+	if maxSize > 0 {
+		r = io.LimitReader(r, maxSize+1)
+	}
+	return io.ReadAll(r)
+}
+
+// brokenLinks lists every broken fetch with the pages that link to it,
+// sorted by URL.
+func brokenLinks(pages map[string]PageData, fetches map[string]FetchResult, normalizer *Normalizer) []BrokenLink {
+	broken := make(map[string]*BrokenLink)
+	for normalizedURL, result := range fetches {
+		if result.Broken() {
+			broken[normalizedURL] = &BrokenLink{FetchResult: result}
+		}
+	}
+
+	for _, pageData := range pages {
+		seen := make(map[string]struct{})
+		for _, link := range pageData.OutgoingLinks {
+			normalizedLink, err := normalizer.Normalize(link)
+			if err != nil {
+				continue
+			}
+			brokenLink, found := broken[normalizedLink]
+			if !found {
+				continue
+			}
+			if _, duplicate := seen[normalizedLink]; duplicate {
+				continue
+			}
+			seen[normalizedLink] = struct{}{}
+			brokenLink.LinkedFrom = append(brokenLink.LinkedFrom, pageData.URL)
+		}
+	}
+
+	links := make([]BrokenLink, 0, len(broken))
+	for _, brokenLink := range broken {
+		sort.Strings(brokenLink.LinkedFrom)
+		links = append(links, *brokenLink)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].URL < links[j].URL
+	})
+	return links
+}
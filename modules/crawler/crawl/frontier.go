@@ -0,0 +1,174 @@
+package crawl
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+)
+
+type crawlItem struct {
+	url           string
+	normalizedURL string
+	depth         int
+}
+
+// frontier is a deduplicating FIFO queue shared by the crawl workers. FIFO
+// order gives a breadth-first crawl. It hands out at most maxPages pages:
+// a page is reserved when a worker takes it and released again if the
+// fetch fails, so the crawl never goes over the limit.
+type frontier struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	queue    []crawlItem
+	seen     map[string]struct{}
+	active   map[string]crawlItem // handed out by next, not done yet
+	maxPages int
+	maxDepth int // negative means unlimited
+	inFlight int
+	crawled  int
+	closed   bool
+}
+
+func newFrontier(maxPages, maxDepth int) *frontier {
+	f := &frontier{
+		seen:     make(map[string]struct{}),
+		active:   make(map[string]crawlItem),
+		maxPages: maxPages,
+		maxDepth: maxDepth,
+	}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+// push queues an item unless it was seen before or is too deep.
+func (f *frontier) push(item crawlItem) (added bool) {
+	if f.maxDepth >= 0 && item.depth > f.maxDepth {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, found := f.seen[item.normalizedURL]; found {
+		return false
+	}
+	f.seen[item.normalizedURL] = struct{}{}
+	f.queue = append(f.queue, item)
+	f.cond.Signal()
+	return true
+}
+
+// markSeen stops a URL from being queued later.
+func (f *frontier) markSeen(normalizedURL string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seen[normalizedURL] = struct{}{}
+}
+
+// next blocks until an item is available. It returns false once the page
+// budget is used up, the frontier is closed, or the queue is empty and no
+// worker can add to it.
+func (f *frontier) next() (crawlItem, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for {
+		if f.closed || f.crawled >= f.maxPages {
+			return crawlItem{}, false
+		}
+		if len(f.queue) > 0 && f.crawled+f.inFlight < f.maxPages {
+			item := f.queue[0]
+			f.queue[0] = crawlItem{} // let the GC reclaim the strings
+			f.queue = f.queue[1:]
+			f.active[item.normalizedURL] = item
+			f.inFlight++
+			return item, true
+		}
+		if f.inFlight == 0 {
+			return crawlItem{}, false
+		}
+		f.cond.Wait()
+	}
+}
+
+// done releases the reservation taken by next. Only crawled pages count
+// towards maxPages.
+func (f *frontier) done(item crawlItem, crawled bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.active, item.normalizedURL)
+	f.inFlight--
+	if crawled {
+		f.crawled++
+	}
+	f.cond.Broadcast()
+}
+
+// close makes every pending and future next call return false.
+func (f *frontier) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	f.cond.Broadcast()
+}
+
+// requeue puts an interrupted item back at the front of the queue so that
+// a checkpoint taken after a cancelled crawl still contains it.
+func (f *frontier) requeue(item crawlItem) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.queue = append([]crawlItem{item}, f.queue...)
+}
+
+// snapshot returns the items still to crawl, the ones being crawled first,
+// and the seen set.
+func (f *frontier) snapshot() (items []crawlItem, seen []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	active := slices.Collect(maps.Values(f.active))
+	sort.Slice(active, func(i, j int) bool {
+		if active[i].depth != active[j].depth {
+			return active[i].depth < active[j].depth
+		}
+		return active[i].normalizedURL < active[j].normalizedURL
+	})
+
+	added := make(map[string]struct{}, len(active)+len(f.queue))
+	for _, item := range slices.Concat(active, f.queue) {
+		if _, duplicate := added[item.normalizedURL]; duplicate {
+			continue
+		}
+		added[item.normalizedURL] = struct{}{}
+		items = append(items, item)
+	}
+	return items, slices.Sorted(maps.Keys(f.seen))
+}
+
+// restore replaces the state of an unused frontier with a snapshot.
+// crawled is the number of pages crawled before the snapshot.
+func (f *frontier) restore(items []crawlItem, seen []string, crawled int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.queue = slices.Clone(items)
+	for _, normalizedURL := range seen {
+		f.seen[normalizedURL] = struct{}{}
+	}
+	for _, item := range items {
+		f.seen[item.normalizedURL] = struct{}{}
+	}
+	f.crawled = crawled
+	f.cond.Broadcast()
+}
+
+// counts returns the number of queued items and of items being crawled.
+func (f *frontier) counts() (queued, inFlight int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.queue), f.inFlight
+}
@@ -0,0 +1,171 @@
+package crawl
+
+import (
+	"math"
+	"slices"
+	"sort"
+)
+
+const (
+	pageRankDamping    = 0.85
+	pageRankIterations = 100
+	pageRankTolerance  = 1e-10
+)
+
+// LinkGraph is the internal link structure of the crawled pages. Links to
+// a redirect source or a duplicate of a page point to the page itself;
+// links leaving the crawled pages and self links are left out.
+type LinkGraph struct {
+	Start   string              // page of the base URL, "" if it was not crawled
+	Pages   []string            // sorted
+	Links   map[string][]string // sorted targets by source page
+	Metrics map[string]PageMetrics
+}
+
+// PageMetrics places a page in the link graph.
+type PageMetrics struct {
+	InDegree  int     `json:"in_degree"`
+	OutDegree int     `json:"out_degree"`
+	Depth     int     `json:"click_depth"` // clicks from the start page, -1 if unreachable
+	PageRank  float64 `json:"pagerank"`
+	Orphan    bool    `json:"orphan,omitempty"` // no crawled page links to it
+}
+
+// LinkGraph returns the link graph of the crawled pages, starting at the
+// base URL.
+func (c *Crawler) LinkGraph() *LinkGraph {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return buildLinkGraph(c.pages, c.baseURL.String(), c.normalizer)
+}
+
+func buildLinkGraph(pages map[string]PageData, startURL string, normalizer *Normalizer) *LinkGraph {
+	// every URL a page was seen under leads to it
+	pageURLs := make(map[string]string)
+	for key, pageData := range pages {
+		pageURLs[key] = pageData.URL
+		for _, rawURL := range append([]string{pageData.URL}, pageData.Alternates...) {
+			if normalizedURL, err := normalizer.Normalize(rawURL); err == nil {
+				pageURLs[normalizedURL] = pageData.URL
+			}
+		}
+	}
+	lookup := func(rawURL string) (string, bool) {
+		normalizedURL, err := normalizer.Normalize(rawURL)
+		if err != nil {
+			return "", false
+		}
+		pageURL, found := pageURLs[normalizedURL]
+		return pageURL, found
+	}
+
+	g := &LinkGraph{
+		Links:   make(map[string][]string, len(pages)),
+		Metrics: make(map[string]PageMetrics, len(pages)),
+	}
+	g.Start, _ = lookup(startURL)
+	for _, pageData := range pages {
+		g.Pages = append(g.Pages, pageData.URL)
+		var targets []string
+		for _, link := range pageData.OutgoingLinks {
+			target, found := lookup(link)
+			if found && target != pageData.URL && !slices.Contains(targets, target) {
+				targets = append(targets, target)
+			}
+		}
+		sort.Strings(targets)
+		g.Links[pageData.URL] = targets
+	}
+	sort.Strings(g.Pages)
+
+	inDegree := make(map[string]int, len(g.Pages))
+	for _, targets := range g.Links {
+		for _, target := range targets {
+			inDegree[target]++
+		}
+	}
+	depths := g.depths()
+	ranks := g.pageRank()
+	for i, pageURL := range g.Pages {
+		depth, reachable := depths[pageURL]
+		if !reachable {
+			depth = -1
+		}
+		g.Metrics[pageURL] = PageMetrics{
+			InDegree:  inDegree[pageURL],
+			OutDegree: len(g.Links[pageURL]),
+			Depth:     depth,
+			PageRank:  ranks[i],
+			Orphan:    inDegree[pageURL] == 0 && pageURL != g.Start,
+		}
+	}
+	return g
+}
+
+// depths walks the graph breadth-first from the start page.
+func (g *LinkGraph) depths() map[string]int {
+	depths := make(map[string]int)
+	if g.Start == "" {
+		return depths
+	}
+	depths[g.Start] = 0
+	queue := []string{g.Start}
+	for len(queue) > 0 {
+		pageURL := queue[0]
+		queue = queue[1:]
+		for _, target := range g.Links[pageURL] {
+			if _, found := depths[target]; !found {
+				depths[target] = depths[pageURL] + 1
+				queue = append(queue, target)
+			}
+		}
+	}
+	return depths
+}
+
+// pageRank computes the PageRank of g.Pages by power iteration. Pages
+// without links spread their rank over all pages, so the ranks sum to 1.
+func (g *LinkGraph) pageRank() []float64 {
+	n := len(g.Pages)
+	if n == 0 {
+		return nil
+	}
+	index := make(map[string]int, n)
+	for i, pageURL := range g.Pages {
+		index[pageURL] = i
+	}
+
+	ranks := make([]float64, n)
+	for i := range ranks {
+		ranks[i] = 1 / float64(n)
+	}
+	next := make([]float64, n)
+	for range pageRankIterations {
+		dangling := 0.0
+		for i, pageURL := range g.Pages {
+			if len(g.Links[pageURL]) == 0 {
+				dangling += ranks[i]
+			}
+		}
+		base := (1-pageRankDamping)/float64(n) + pageRankDamping*dangling/float64(n)
+		for i := range next {
+			next[i] = base
+		}
+		for i, pageURL := range g.Pages {
+			targets := g.Links[pageURL]
+			for _, target := range targets {
+				next[index[target]] += pageRankDamping * ranks[i] / float64(len(targets))
+			}
+		}
+
+		change := 0.0
+		for i := range ranks {
+			change += math.Abs(next[i] - ranks[i])
+		}
+		ranks, next = next, ranks
+		if change < pageRankTolerance {
+			break
+		}
+	}
+	return ranks
+}
@@ -0,0 +1,86 @@
+package crawl
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestBuildLinkGraph(t *testing.T) {
+	pages := map[string]PageData{
+		"blog.test.dev": {
+			URL:           "https://blog.test.dev/",
+			OutgoingLinks: []string{"https://blog.test.dev/a", "https://blog.test.dev/old-b", "https://blog.test.dev/a#top", "https://other.dev/"},
+		},
+		"blog.test.dev/a": {
+			URL:           "https://blog.test.dev/a",
+			OutgoingLinks: []string{"https://blog.test.dev/a", "https://blog.test.dev/b"},
+		},
+		"blog.test.dev/b": {
+			URL:        "https://blog.test.dev/b",
+			Alternates: []string{"https://blog.test.dev/old-b"},
+		},
+		"blog.test.dev/orphan": {
+			URL:           "https://blog.test.dev/orphan",
+			OutgoingLinks: []string{"https://blog.test.dev/"},
+		},
+	}
+
+	g := buildLinkGraph(pages, "https://blog.test.dev", DefaultNormalizer())
+
+	if g.Start != "https://blog.test.dev/" {
+		t.Errorf("expected start %q, got %q", "https://blog.test.dev/", g.Start)
+	}
+	expectedLinks := map[string][]string{
+		"https://blog.test.dev/":       {"https://blog.test.dev/a", "https://blog.test.dev/b"},
+		"https://blog.test.dev/a":      {"https://blog.test.dev/b"},
+		"https://blog.test.dev/b":      nil,
+		"https://blog.test.dev/orphan": {"https://blog.test.dev/"},
+	}
+	if !reflect.DeepEqual(g.Links, expectedLinks) {
+		t.Errorf("expected %v, got %v", expectedLinks, g.Links)
+	}
+
+	tests := []struct {
+		url       string
+		inDegree  int
+		outDegree int
+		depth     int
+		orphan    bool
+	}{
+		{"https://blog.test.dev/", 1, 2, 0, false},
+		{"https://blog.test.dev/a", 1, 1, 1, false},
+		{"https://blog.test.dev/b", 2, 0, 1, false},
+		{"https://blog.test.dev/orphan", 0, 1, -1, true},
+	}
+	for _, tc := range tests {
+		metrics := g.Metrics[tc.url]
+		if metrics.InDegree != tc.inDegree || metrics.OutDegree != tc.outDegree || metrics.Depth != tc.depth || metrics.Orphan != tc.orphan {
+			t.Errorf("%s: expected %+v, got %+v", tc.url, tc, metrics)
+		}
+	}
+
+	sum := 0.0
+	for _, metrics := range g.Metrics {
+		sum += metrics.PageRank
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("expected the ranks to sum to 1, got %v", sum)
+	}
+	rank := func(pageURL string) float64 { return g.Metrics[pageURL].PageRank }
+	if !(rank("https://blog.test.dev/b") > rank("https://blog.test.dev/a") && rank("https://blog.test.dev/a") > rank("https://blog.test.dev/orphan")) {
+		t.Errorf("expected b > a > orphan, got %+v", g.Metrics)
+	}
+}
+
+func TestPageRankCycle(t *testing.T) {
+	g := &LinkGraph{
+		Pages: []string{"a", "b", "c"},
+		Links: map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}},
+	}
+	for i, rank := range g.pageRank() {
+		if math.Abs(rank-1.0/3) > 1e-9 {
+			t.Errorf("%s: expected %v, got %v", g.Pages[i], 1.0/3, rank)
+		}
+	}
+}
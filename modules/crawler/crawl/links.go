@@ -0,0 +1,267 @@
+package crawl
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// LinkKind tells what a link points to.
+type LinkKind string
+
+const (
+	LinkAnchor     LinkKind = "anchor"     // <a href>, <area href>
+	LinkImage      LinkKind = "image"      // <img>, <picture><source>, video posters
+	LinkStylesheet LinkKind = "stylesheet" // <link rel=stylesheet>
+	LinkPreload    LinkKind = "preload"    // <link rel=preload|modulepreload>
+	LinkAlternate  LinkKind = "alternate"  // <link rel=alternate>, e.g. feeds and translations
+	LinkScript     LinkKind = "script"     // <script src>
+	LinkIframe     LinkKind = "iframe"     // <iframe src>
+	LinkMedia      LinkKind = "media"      // <video>, <audio> and their <source>, <track>
+	LinkStyle      LinkKind = "style"      // url() in style attributes and <style> blocks
+)
+
+// Link is a reference from a page to another URL.
+type Link struct {
+	URL  string   `json:"url"`
+	Kind LinkKind `json:"kind"`
+	// Text is the anchor text, or the alt text of images.
+	Text     string `json:"text,omitempty"`
+	Nofollow bool   `json:"nofollow,omitempty"`
+	// MissingAlt is set for <img> elements without an alt attribute.
+	MissingAlt bool `json:"missing_alt,omitempty"`
+}
+
+// IsAsset reports whether the link is loaded as part of the page rather
+// than navigated to.
+func (l Link) IsAsset() bool {
+	switch l.Kind {
+	case LinkAnchor, LinkAlternate:
+		return false
+	}
+	return true
+}
+
+var cssURLPattern = regexp.MustCompile(`url\(\s*(?:"([^"]*)"|'([^']*)'|([^)"'\s]*))\s*\)`)
+
+// getLinksFromHTML returns every link of the page in document order,
+// resolved against the base URL of getBaseURL.
+func getLinksFromHTML(doc *goquery.Document, base *url.URL) []Link {
+	if base == nil {
+		return nil
+	}
+
+	var links []Link
+	add := func(ref string, kind LinkKind, text string, nofollow bool) {
+		resolved, ok := resolveLink(base, ref)
+		if !ok {
+			return
+		}
+		links = append(links, Link{URL: resolved, Kind: kind, Text: text, Nofollow: nofollow})
+	}
+
+	doc.Find("*").Each(func(_ int, item *goquery.Selection) {
+		switch goquery.NodeName(item) {
+		case "a", "area":
+			if href, exist := item.Attr("href"); exist {
+				add(href, LinkAnchor, anchorText(item), hasRel(item, "nofollow"))
+			}
+		case "img":
+			alt, hasAlt := item.Attr("alt")
+			alt = collapseSpace(alt)
+			first := len(links)
+			if src, exist := item.Attr("src"); exist {
+				add(src, LinkImage, alt, false)
+			}
+			srcset, _ := item.Attr("srcset")
+			for _, candidate := range srcsetURLs(srcset) {
+				add(candidate, LinkImage, alt, false)
+			}
+			for i := first; i < len(links); i++ {
+				links[i].MissingAlt = !hasAlt
+			}
+		case "source":
+			kind := LinkMedia
+			if goquery.NodeName(item.Parent()) == "picture" {
+				kind = LinkImage
+			}
+			if src, exist := item.Attr("src"); exist {
+				add(src, kind, "", false)
+			}
+			srcset, _ := item.Attr("srcset")
+			for _, candidate := range srcsetURLs(srcset) {
+				add(candidate, kind, "", false)
+			}
+		case "link":
+			href, exist := item.Attr("href")
+			if !exist {
+				return
+			}
+			nofollow := hasRel(item, "nofollow")
+			switch {
+			case hasRel(item, "stylesheet"):
+				add(href, LinkStylesheet, "", nofollow)
+			case hasRel(item, "preload"), hasRel(item, "modulepreload"):
+				add(href, LinkPreload, "", nofollow)
+			case hasRel(item, "alternate"):
+				title, _ := item.Attr("title")
+				add(href, LinkAlternate, collapseSpace(title), nofollow)
+			}
+		case "script":
+			if src, exist := item.Attr("src"); exist {
+				add(src, LinkScript, "", false)
+			}
+		case "iframe":
+			if src, exist := item.Attr("src"); exist {
+				title, _ := item.Attr("title")
+				add(src, LinkIframe, collapseSpace(title), false)
+			}
+		case "video", "audio", "track":
+			if src, exist := item.Attr("src"); exist {
+				add(src, LinkMedia, "", false)
+			}
+			if poster, exist := item.Attr("poster"); exist {
+				add(poster, LinkImage, "", false)
+			}
+		case "style":
+			for _, ref := range cssURLs(item.Text()) {
+				add(ref, LinkStyle, "", false)
+			}
+		}
+
+		if style, exist := item.Attr("style"); exist {
+			for _, ref := range cssURLs(style) {
+				add(ref, LinkStyle, "", false)
+			}
+		}
+	})
+	return links
+}
+
+// resolveLink makes ref absolute. Fragment-only, javascript:, data: and
+// similar references are not links to other documents and are dropped.
+func resolveLink(base *url.URL, ref string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "#") {
+		return "", false
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return "", false
+	}
+
+	resolved := base.ResolveReference(refURL)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return "", false
+	}
+	if refURL.IsAbs() {
+		return ref, true
+	}
+	if resolved.Path == "/" && base.Path == "" && resolved.RawQuery == "" {
+		// keep the site root in the same form as the page URL
+		resolved.Path = ""
+	}
+	return resolved.String(), true
+}
+
+// srcsetURLs returns the candidate URLs of a srcset attribute. Following
+// the HTML grammar, a URL runs up to whitespace and may contain commas;
+// its descriptor runs up to the next comma outside parentheses.
+func srcsetURLs(srcset string) []string {
+	isSpace := func(r rune) bool { return strings.ContainsRune(" \t\n\f\r", r) }
+
+	var urls []string
+	for {
+		srcset = strings.TrimLeftFunc(srcset, func(r rune) bool { return r == ',' || isSpace(r) })
+		if srcset == "" {
+			return urls
+		}
+		end := strings.IndexFunc(srcset, isSpace)
+		if end < 0 {
+			end = len(srcset)
+		}
+		candidate := srcset[:end]
+		srcset = srcset[end:]
+
+		// a URL ending in commas has no descriptor
+		if trimmed := strings.TrimRight(candidate, ","); trimmed != candidate {
+			urls = append(urls, trimmed)
+			continue
+		}
+		urls = append(urls, candidate)
+
+		depth := 0
+		end = strings.IndexFunc(srcset, func(r rune) bool {
+			switch {
+			case r == '(':
+				depth++
+			case r == ')' && depth > 0:
+				depth--
+			case r == ',' && depth == 0:
+				return true
+			}
+			return false
+		})
+		if end < 0 {
+			return urls
+		}
+		srcset = srcset[end+1:]
+	}
+}
+
+// cssURLs returns the url() references of a CSS snippet.
+func cssURLs(css string) []string {
+	var urls []string
+	for _, match := range cssURLPattern.FindAllStringSubmatch(css, -1) {
+		for _, ref := range match[1:] {
+			if ref != "" && !strings.HasPrefix(ref, "data:") {
+				urls = append(urls, ref)
+				break
+			}
+		}
+	}
+	return urls
+}
+
+func hasRel(item *goquery.Selection, rel string) bool {
+	value, _ := item.Attr("rel")
+	return slices.Contains(strings.Fields(strings.ToLower(value)), rel)
+}
+
+// anchorText is the link text, or the alt text of a linked image.
+func anchorText(item *goquery.Selection) string {
+	if text := collapseSpace(item.Text()); text != "" {
+		return text
+	}
+	alt, _ := item.Find("img[alt]").First().Attr("alt")
+	return collapseSpace(alt)
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// linkURLs returns the URLs of the links of the given kinds.
+func linkURLs(links []Link, kinds ...LinkKind) []string {
+	var urls []string
+	for _, link := range links {
+		if slices.Contains(kinds, link.Kind) {
+			urls = append(urls, link.URL)
+		}
+	}
+	return urls
+}
+
+// hasRobotsDirective reports whether a meta robots value such as
+// "noindex, nofollow" contains directive. "none" implies all of them.
+func hasRobotsDirective(metaRobots, directive string) bool {
+	for _, value := range strings.Split(strings.ToLower(metaRobots), ",") {
+		if strings.TrimSpace(value) == directive || strings.TrimSpace(value) == "none" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package crawl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetLinksFromHTML(t *testing.T) {
+	inputURL := "https://blog.web.dev/posts/first"
+	inputBody := `<html><head>
+        <base href="/assets/">
+        <link rel="stylesheet" href="site.css">
+        <link rel="preload" href="font.woff2" as="font">
+        <link rel="alternate" type="application/rss+xml" title="Feed" href="/feed.xml">
+        <link rel="icon" href="favicon.ico">
+        <script src="app.js"></script>
+        <style>body { background: url('bg.png') }</style>
+    </head><body>
+        <a href="/about" rel="nofollow">About
+            us</a>
+        <a href="https://other.dev/"><img src="logo.png" alt="Other"></a>
+        <a href="#top">Top</a>
+        <a href="mailto:me@web.dev">Mail</a>
+        <img src="a.png" srcset="a-1x.png 1x, a-2x.png 2x">
+        <picture><source srcset="b.webp"><img src="b.png" alt="B"></picture>
+        <iframe src="https://video.dev/embed/1" title="Video"></iframe>
+        <video src="movie.mp4" poster="poster.jpg"><track src="subs.vtt"></video>
+        <audio><source src="song.mp3"></audio>
+        <div style="background-image: url(&quot;hero.jpg&quot;)"></div>
+    </body></html>`
+
+	doc := mustParseHTML(t, inputBody)
+	actual := getLinksFromHTML(doc, getBaseURL(doc, inputURL))
+
+	expected := []Link{
+		{URL: "https://blog.web.dev/assets/site.css", Kind: LinkStylesheet},
+		{URL: "https://blog.web.dev/assets/font.woff2", Kind: LinkPreload},
+		{URL: "https://blog.web.dev/feed.xml", Kind: LinkAlternate, Text: "Feed"},
+		{URL: "https://blog.web.dev/assets/app.js", Kind: LinkScript},
+		{URL: "https://blog.web.dev/assets/bg.png", Kind: LinkStyle},
+		{URL: "https://blog.web.dev/about", Kind: LinkAnchor, Text: "About us", Nofollow: true},
+		{URL: "https://other.dev/", Kind: LinkAnchor, Text: "Other"},
+		{URL: "https://blog.web.dev/assets/logo.png", Kind: LinkImage, Text: "Other"},
+		{URL: "https://blog.web.dev/assets/a.png", Kind: LinkImage, MissingAlt: true},
+		{URL: "https://blog.web.dev/assets/a-1x.png", Kind: LinkImage, MissingAlt: true},
+		{URL: "https://blog.web.dev/assets/a-2x.png", Kind: LinkImage, MissingAlt: true},
+		{URL: "https://blog.web.dev/assets/b.webp", Kind: LinkImage},
+		{URL: "https://blog.web.dev/assets/b.png", Kind: LinkImage, Text: "B"},
+		{URL: "https://video.dev/embed/1", Kind: LinkIframe, Text: "Video"},
+		{URL: "https://blog.web.dev/assets/movie.mp4", Kind: LinkMedia},
+		{URL: "https://blog.web.dev/assets/poster.jpg", Kind: LinkImage},
+		{URL: "https://blog.web.dev/assets/subs.vtt", Kind: LinkMedia},
+		{URL: "https://blog.web.dev/assets/song.mp3", Kind: LinkMedia},
+		{URL: "https://blog.web.dev/assets/hero.jpg", Kind: LinkStyle},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestSrcsetURLs(t *testing.T) {
+	tests := []struct {
+		srcset   string
+		expected []string
+	}{
+		{"", nil},
+		{"a.png", []string{"a.png"}},
+		{"a-1x.png 1x, a-2x.png 2x", []string{"a-1x.png", "a-2x.png"}},
+		{"a.png, b.png 2x,c.png", []string{"a.png", "b.png", "c.png"}},
+		{"img.jpg?w=1,2 2x, img.jpg?w=3,4 3x", []string{"img.jpg?w=1,2", "img.jpg?w=3,4"}},
+		{"/cdn/w_100,h_50/a.jpg 100w,\n/cdn/w_200,h_100/a.jpg 200w", []string{"/cdn/w_100,h_50/a.jpg", "/cdn/w_200,h_100/a.jpg"}},
+		{"a.png (max-width: 10px, 2x), b.png", []string{"a.png", "b.png"}},
+	}
+
+	for _, tc := range tests {
+		if actual := srcsetURLs(tc.srcset); !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%q: expected %q, got %q", tc.srcset, tc.expected, actual)
+		}
+	}
+}
+
+func TestHasRobotsDirective(t *testing.T) {
+	tests := []struct {
+		metaRobots string
+		expected   bool
+	}{
+		{"", false},
+		{"noindex", false},
+		{"noindex, NoFollow", true},
+		{"none", true},
+	}
+
+	for _, tc := range tests {
+		if actual := hasRobotsDirective(tc.metaRobots, "nofollow"); actual != tc.expected {
+			t.Errorf("%q: expected %v, got %v", tc.metaRobots, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package crawl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// fetchDurationBuckets are the upper bounds in seconds of the fetch
+// latency histogram.
+var fetchDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics collects counters of the crawls it is passed to with
+// WithMetrics and serves them in the Prometheus text exposition format.
+// One Metrics can be shared by several crawls; the gauges add up their
+// queues and workers.
+type Metrics struct {
+	mu            sync.Mutex
+	fetches       map[string]uint64 // by status code, "error" without a response
+	durationCount []uint64          // per bucket of fetchDurationBuckets, +Inf last
+	durationSum   time.Duration
+	pages         uint64
+	requests      uint64
+	bytes         uint64
+	running       map[*Crawler]struct{}
+}
+
+// NewMetrics returns an empty Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		fetches:       make(map[string]uint64),
+		durationCount: make([]uint64, len(fetchDurationBuckets)+1),
+		running:       make(map[*Crawler]struct{}),
+	}
+}
+
+// observeFetch records a page fetch. Like the other recording methods it
+// does nothing on a nil Metrics, so a crawl without metrics needs no
+// checks.
+func (m *Metrics) observeFetch(result FetchResult) {
+	if m == nil {
+		return
+	}
+	status := "error"
+	if result.StatusCode != 0 {
+		status = strconv.Itoa(result.StatusCode)
+	}
+	bucket, _ := slices.BinarySearch(fetchDurationBuckets, result.ResponseTime.Seconds())
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fetches[status]++
+	m.durationCount[bucket]++
+	m.durationSum += result.ResponseTime
+}
+
+func (m *Metrics) addPage() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pages++
+}
+
+func (m *Metrics) addRequest() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests++
+}
+
+func (m *Metrics) addBytes(n int) {
+	if m == nil || n <= 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bytes += uint64(n)
+}
+
+// track adds a running crawl to the gauges until the returned func is
+// called.
+func (m *Metrics) track(c *Crawler) (untrack func()) {
+	if m == nil {
+		return func() {}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.running[c] = struct{}{}
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.running, c)
+	}
+}
+
+// ServeHTTP writes the metrics for a Prometheus scrape.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteText(w)
+}
+
+// WriteText writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) WriteText(w io.Writer) error {
+	m.mu.Lock()
+	running := make([]*Crawler, 0, len(m.running))
+	for c := range m.running {
+		running = append(running, c)
+	}
+	fetches := maps.Clone(m.fetches)
+	durationCount := slices.Clone(m.durationCount)
+	durationSum, pages, requests, bytes := m.durationSum, m.pages, m.requests, m.bytes
+	m.mu.Unlock()
+
+	// the crawls lock their frontiers, so they are read without m.mu
+	var queued, active int
+	for _, c := range running {
+		q, inFlight := c.frontier.counts()
+		queued += q
+		active += inFlight
+	}
+
+	buf := bufio.NewWriter(w)
+	writeHeader := func(name, kind, help string) {
+		fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	writeHeader("crawler_fetches_total", "counter", `Page fetches by final HTTP status, "error" if there was no response.`)
+	for _, status := range slices.Sorted(maps.Keys(fetches)) {
+		fmt.Fprintf(buf, "crawler_fetches_total{status=%q} %d\n", status, fetches[status])
+	}
+
+	writeHeader("crawler_fetch_duration_seconds", "histogram", "Time to fetch a page, redirects and retries included.")
+	var cumulative uint64
+	for i, count := range durationCount {
+		cumulative += count
+		le := "+Inf"
+		if i < len(fetchDurationBuckets) {
+			le = strconv.FormatFloat(fetchDurationBuckets[i], 'g', -1, 64)
+		}
+		fmt.Fprintf(buf, "crawler_fetch_duration_seconds_bucket{le=%q} %d\n", le, cumulative)
+	}
+	fmt.Fprintf(buf, "crawler_fetch_duration_seconds_sum %s\n", strconv.FormatFloat(durationSum.Seconds(), 'g', -1, 64))
+	fmt.Fprintf(buf, "crawler_fetch_duration_seconds_count %d\n", cumulative)
+
+	writeHeader("crawler_pages_total", "counter", "Crawled pages.")
+	fmt.Fprintf(buf, "crawler_pages_total %d\n", pages)
+	writeHeader("crawler_requests_total", "counter", "HTTP requests sent for pages, assets and external links, retries included.")
+	fmt.Fprintf(buf, "crawler_requests_total %d\n", requests)
+	writeHeader("crawler_response_bytes_total", "counter", "Response body bytes downloaded.")
+	fmt.Fprintf(buf, "crawler_response_bytes_total %d\n", bytes)
+	writeHeader("crawler_queue_depth", "gauge", "URLs waiting to be crawled.")
+	fmt.Fprintf(buf, "crawler_queue_depth %d\n", queued)
+	writeHeader("crawler_active_workers", "gauge", "Workers crawling a page.")
+	fmt.Fprintf(buf, "crawler_active_workers %d\n", active)
+	writeHeader("crawler_running_crawls", "gauge", "Crawls in progress.")
+	fmt.Fprintf(buf, "crawler_running_crawls %d\n", len(running))
+	return buf.Flush()
+}
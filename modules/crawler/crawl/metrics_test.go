@@ -0,0 +1,74 @@
+package crawl
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsWriteText(t *testing.T) {
+	m := NewMetrics()
+	m.observeFetch(FetchResult{StatusCode: 200, ResponseTime: 30 * time.Millisecond})
+	m.observeFetch(FetchResult{StatusCode: 200, ResponseTime: 100 * time.Millisecond})
+	m.observeFetch(FetchResult{StatusCode: 404, ResponseTime: 2 * time.Second})
+	m.observeFetch(FetchResult{ResponseTime: 30 * time.Second})
+	m.addPage()
+	m.addRequest()
+	m.addBytes(512)
+
+	var buf bytes.Buffer
+	if err := m.WriteText(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, line := range []string{
+		"# TYPE crawler_fetches_total counter",
+		`crawler_fetches_total{status="200"} 2`,
+		`crawler_fetches_total{status="404"} 1`,
+		`crawler_fetches_total{status="error"} 1`,
+		"# TYPE crawler_fetch_duration_seconds histogram",
+		`crawler_fetch_duration_seconds_bucket{le="0.05"} 1`,
+		`crawler_fetch_duration_seconds_bucket{le="0.1"} 2`,
+		`crawler_fetch_duration_seconds_bucket{le="2.5"} 3`,
+		`crawler_fetch_duration_seconds_bucket{le="10"} 3`,
+		`crawler_fetch_duration_seconds_bucket{le="+Inf"} 4`,
+		"crawler_fetch_duration_seconds_sum 32.13",
+		"crawler_fetch_duration_seconds_count 4",
+		"crawler_pages_total 1",
+		"crawler_requests_total 1",
+		"crawler_response_bytes_total 512",
+		"crawler_queue_depth 0",
+		"crawler_active_workers 0",
+		"crawler_running_crawls 0",
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("expected line %q in\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestCrawlMetrics(t *testing.T) {
+	server := newTestSite(t)
+	m := NewMetrics()
+	c := newTestCrawler(t, server.URL+"/p/1", WithConcurrency(2), WithMaxDepth(1), WithMetrics(m))
+
+	var buf bytes.Buffer
+	if err := m.WriteText(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	progress := c.Progress()
+	for _, line := range []string{
+		`crawler_fetches_total{status="200"} 3`,
+		`crawler_fetches_total{status="404"} 1`,
+		"crawler_fetch_duration_seconds_count 4",
+		"crawler_pages_total 3",
+		"crawler_requests_total 4",
+		"crawler_response_bytes_total " + strconv.FormatInt(progress.Bytes, 10),
+		"crawler_running_crawls 0",
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("expected line %q in\n%s", line, buf.String())
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package crawl
+
+import (
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Normalizer turns URLs into page identities so that the different
+// spellings of one page map to the same key. The key has no fragment and,
+// unless IncludeScheme is set, no scheme.
+type Normalizer struct {
+	// IncludeScheme keeps http and https versions of a page apart.
+	IncludeScheme bool
+	// IgnoreQuery drops the query string so ?page=2 is the same page as
+	// page 1.
+	IgnoreQuery bool
+	// StripParams lists query parameters to remove, e.g. tracking
+	// parameters. A trailing "*" matches any parameter with that prefix.
+	StripParams []string
+	// IndexFiles are file names that mean their directory, e.g. index.html.
+	IndexFiles []string
+}
+
+// DefaultNormalizer strips common tracking parameters and index files and
+// keeps the rest of the query.
+func DefaultNormalizer() *Normalizer {
+	return &Normalizer{
+		StripParams: []string{"utm_*", "fbclid", "gclid"},
+		IndexFiles:  []string{"index.html", "index.htm"},
+	}
+}
+
+var defaultNormalizer = DefaultNormalizer()
+
+func normalizeURL(inputURL string) (string, error) {
+	return defaultNormalizer.Normalize(inputURL)
+}
+
+// Normalize returns the identity of inputURL: lowercase scheme and host,
+// no default port, canonical percent-encoding, no dot segments, index
+// files or trailing slash, and sorted query parameters.
+func (n *Normalizer) Normalize(inputURL string) (string, error) {
+	parsedURL, err := url.Parse(inputURL)
+	if err != nil {
+		return "", err
+	}
+
+	scheme := strings.ToLower(parsedURL.Scheme)
+	host := strings.TrimSuffix(strings.ToLower(parsedURL.Hostname()), ".")
+	if port := parsedURL.Port(); port != "" && !isDefaultPort(scheme, port) {
+		host += ":" + port
+	}
+
+	var b strings.Builder
+	if n.IncludeScheme && scheme != "" {
+		b.WriteString(scheme + "://")
+	}
+	b.WriteString(host)
+	b.WriteString(n.normalizePath(parsedURL.EscapedPath()))
+
+	if !n.IgnoreQuery {
+		if query := n.normalizeQuery(parsedURL.RawQuery); query != "" {
+			b.WriteString("?" + query)
+		}
+	}
+	return b.String(), nil
+}
+
+func (n *Normalizer) normalizePath(escapedPath string) string {
+	normalizedPath := normalizeEscapes(escapedPath)
+
+	if strings.Contains(normalizedPath, "/.") {
+		trailingSlash := strings.HasSuffix(normalizedPath, "/") ||
+			strings.HasSuffix(normalizedPath, "/.") || strings.HasSuffix(normalizedPath, "/..")
+		normalizedPath = path.Clean(normalizedPath)
+		if trailingSlash && normalizedPath != "/" {
+			normalizedPath += "/"
+		}
+	}
+
+	dir, file := path.Split(normalizedPath)
+	for _, indexFile := range n.IndexFiles {
+		if strings.EqualFold(file, indexFile) {
+			normalizedPath = dir
+			break
+		}
+	}
+
+	// remove trailing slash if present
+	return strings.TrimSuffix(normalizedPath, "/")
+}
+
+// normalizeQuery sorts the parameters and drops the stripped ones. Values
+// keep their original encoding apart from percent-escape normalization.
+func (n *Normalizer) normalizeQuery(rawQuery string) string {
+	var params []string
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(param, "=")
+		if decodedKey, err := url.QueryUnescape(key); err == nil {
+			key = decodedKey
+		}
+		if n.stripParam(key) {
+			continue
+		}
+		params = append(params, normalizeEscapes(param))
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+func (n *Normalizer) stripParam(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range n.StripParams {
+		pattern = strings.ToLower(pattern)
+		if prefix, isPrefix := strings.CutSuffix(pattern, "*"); isPrefix {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func isDefaultPort(scheme, port string) bool {
+	return (scheme == "http" && port == "80") || (scheme == "https" && port == "443")
+}
+
+// normalizeEscapes decodes percent-escaped unreserved characters and
+// uppercases the hex digits of the remaining escapes (RFC 3986 6.2.2).
+func normalizeEscapes(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+		decoded := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(decoded) {
+			b.WriteByte(decoded)
+		} else {
+			b.WriteString("%" + strings.ToUpper(s[i+1:i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+func isUnreserved(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
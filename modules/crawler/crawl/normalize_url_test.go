@@ -0,0 +1,169 @@
+package crawl
+
+import (
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		inputURL string
+		expected string
+	}{
+		{
+			name:     "with https",
+			inputURL: "https://blog.test.dev/path",
+			expected: "blog.test.dev/path",
+		},
+		{
+			name:     "with http",
+			inputURL: "http://blog.test.dev/path",
+			expected: "blog.test.dev/path",
+		},
+		{
+			name:     "start with http and ends with slash",
+			inputURL: "http://blog.test.dev/path/",
+			expected: "blog.test.dev/path",
+		},
+		{
+			name:     "start with https and ends with slash",
+			inputURL: "https://blog.test.dev/path/",
+			expected: "blog.test.dev/path",
+		},
+		{
+			name:     "uppercase scheme and host",
+			inputURL: "HTTPS://Blog.Test.DEV/Path",
+			expected: "blog.test.dev/Path",
+		},
+		{
+			name:     "default http port",
+			inputURL: "http://blog.test.dev:80/path",
+			expected: "blog.test.dev/path",
+		},
+		{
+			name:     "default https port",
+			inputURL: "https://blog.test.dev:443/path",
+			expected: "blog.test.dev/path",
+		},
+		{
+			name:     "non-default port is kept",
+			inputURL: "https://blog.test.dev:8443/path",
+			expected: "blog.test.dev:8443/path",
+		},
+		{
+			name:     "query is kept",
+			inputURL: "https://blog.test.dev/posts?page=2",
+			expected: "blog.test.dev/posts?page=2",
+		},
+		{
+			name:     "query parameters are sorted",
+			inputURL: "https://blog.test.dev/search?q=go&lang=en",
+			expected: "blog.test.dev/search?lang=en&q=go",
+		},
+		{
+			name:     "tracking parameters are stripped",
+			inputURL: "https://blog.test.dev/path?utm_source=x&utm_medium=y&id=1&fbclid=abc&gclid=def",
+			expected: "blog.test.dev/path?id=1",
+		},
+		{
+			name:     "only tracking parameters",
+			inputURL: "https://blog.test.dev/path/?utm_campaign=launch",
+			expected: "blog.test.dev/path",
+		},
+		{
+			name:     "fragment is dropped",
+			inputURL: "https://blog.test.dev/path#section",
+			expected: "blog.test.dev/path",
+		},
+		{
+			name:     "unreserved characters are decoded",
+			inputURL: "https://blog.test.dev/%7Euser/%61bout",
+			expected: "blog.test.dev/~user/about",
+		},
+		{
+			name:     "escape hex digits are uppercased",
+			inputURL: "https://blog.test.dev/a%2fb?q=a%2bb",
+			expected: "blog.test.dev/a%2Fb?q=a%2Bb",
+		},
+		{
+			name:     "dot segments",
+			inputURL: "https://blog.test.dev/a/./b/../c/",
+			expected: "blog.test.dev/a/c",
+		},
+		{
+			name:     "index.html",
+			inputURL: "https://blog.test.dev/docs/index.html",
+			expected: "blog.test.dev/docs",
+		},
+		{
+			name:     "root index.htm",
+			inputURL: "https://blog.test.dev/index.htm",
+			expected: "blog.test.dev",
+		},
+		{
+			name:     "root",
+			inputURL: "https://blog.test.dev/",
+			expected: "blog.test.dev",
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := normalizeURL(tc.inputURL)
+			if err != nil {
+				t.Errorf("Test %v - '%s' FAIL: unexpected error: %v", i, tc.name, err)
+				return
+			}
+			if actual != tc.expected {
+				t.Errorf("Test %v - %s FAIL: expected URL: %v, actual: %v", i, tc.name, tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestNormalizerOptions(t *testing.T) {
+	tests := []struct {
+		name       string
+		normalizer *Normalizer
+		inputURL   string
+		expected   string
+	}{
+		{
+			name:       "include scheme",
+			normalizer: &Normalizer{IncludeScheme: true},
+			inputURL:   "HTTP://blog.test.dev/path",
+			expected:   "http://blog.test.dev/path",
+		},
+		{
+			name:       "ignore query",
+			normalizer: &Normalizer{IgnoreQuery: true},
+			inputURL:   "https://blog.test.dev/posts?page=2",
+			expected:   "blog.test.dev/posts",
+		},
+		{
+			name:       "custom stripped parameters",
+			normalizer: &Normalizer{StripParams: []string{"session", "ref_*"}},
+			inputURL:   "https://blog.test.dev/posts?session=1&ref_src=tw&utm_source=x",
+			expected:   "blog.test.dev/posts?utm_source=x",
+		},
+		{
+			name:       "no index files",
+			normalizer: &Normalizer{},
+			inputURL:   "https://blog.test.dev/index.html",
+			expected:   "blog.test.dev/index.html",
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := tc.normalizer.Normalize(tc.inputURL)
+			if err != nil {
+				t.Errorf("Test %v - '%s' FAIL: unexpected error: %v", i, tc.name, err)
+				return
+			}
+			if actual != tc.expected {
+				t.Errorf("Test %v - %s FAIL: expected URL: %v, actual: %v", i, tc.name, tc.expected, actual)
+			}
+		})
+	}
+}
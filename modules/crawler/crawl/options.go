@@ -0,0 +1,298 @@
+package crawl
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// Option configures a Crawler.
+type Option func(*Crawler)
+
+// WithConcurrency sets the number of workers fetching pages.
+func WithConcurrency(n int) Option {
+	return func(c *Crawler) {
+		c.concurrency = n
+	}
+}
+
+// WithMaxPages sets the exact number of pages after which the crawl stops.
+func WithMaxPages(n int) Option {
+	return func(c *Crawler) {
+		c.maxPages = n
+	}
+}
+
+// WithMaxDepth limits how many links away from the seeds the crawl goes.
+// A negative depth means unlimited.
+func WithMaxDepth(depth int) Option {
+	return func(c *Crawler) {
+		c.maxDepth = depth
+	}
+}
+
+// WithUserAgent sets the User-Agent header and the robots.txt agent.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Crawler) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithHTTPClient sets the client used for every request instead of the
+// built-in one; WithTimeouts and WithProxy then have no effect.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Crawler) {
+		c.client = client
+	}
+}
+
+// WithTimeouts sets how long the built-in client waits to connect, for
+// the response headers once the request is sent, and for the whole
+// request including the body; 0 for no limit. The defaults are 10s, 15s
+// and 30s.
+func WithTimeouts(connect, header, total time.Duration) Option {
+	return func(c *Crawler) {
+		c.connectTimeout = connect
+		c.headerTimeout = header
+		c.timeout = total
+	}
+}
+
+// WithProxy sends the requests of the built-in client through proxyURL.
+// Without it the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+// variables are used.
+func WithProxy(proxyURL *url.URL) Option {
+	return func(c *Crawler) {
+		c.proxy = proxyURL
+	}
+}
+
+// WithMaxBodySize sets how many bytes of a page are read after
+// decompression, 0 for no limit. Longer pages are cut and flagged, see
+// FetchResult.Truncated. The default is 10 MiB.
+func WithMaxBodySize(size int64) Option {
+	return func(c *Crawler) {
+		c.maxBodySize = size
+	}
+}
+
+// WithHeader sets a header on every request to the crawled site, e.g. a
+// token for a staging site. Requests to other hosts don't get it, not even
+// through a redirect.
+func WithHeader(name, value string) Option {
+	return func(c *Crawler) {
+		if c.headers == nil {
+			c.headers = make(http.Header)
+		}
+		c.headers.Add(name, value)
+	}
+}
+
+// WithCookies sends cookies to the crawled site. The cookies the site sets
+// during the crawl are kept as well, so a login session carries over.
+func WithCookies(cookies ...*http.Cookie) Option {
+	return func(c *Crawler) {
+		c.cookies = append(c.cookies, cookies...)
+	}
+}
+
+// WithBasicAuth sends HTTP basic auth credentials to the crawled site.
+// Requests to other hosts don't get them.
+func WithBasicAuth(username, password string) Option {
+	return func(c *Crawler) {
+		c.basicAuth = url.UserPassword(username, password)
+	}
+}
+
+// WithDelay sets the minimum time between the starts of two requests to
+// the same host, 0 for no limit. A Crawl-delay in robots.txt takes
+// precedence. The default is 500ms.
+func WithDelay(delay time.Duration) Option {
+	return func(c *Crawler) {
+		c.delay = delay
+	}
+}
+
+// WithRateLimit limits the requests to each host to requestsPerSecond and
+// to maxInFlight at once; 0 disables either limit. The default is 2
+// requests per second and 2 in flight. Hosts answering 429 or 503 are
+// slowed down further and Retry-After is honored.
+func WithRateLimit(requestsPerSecond float64, maxInFlight int) Option {
+	return func(c *Crawler) {
+		c.delay = 0
+		if requestsPerSecond != 0 {
+			c.delay = time.Duration(float64(time.Second) / requestsPerSecond)
+		}
+		c.maxInFlight = maxInFlight
+	}
+}
+
+// WithRetries sets how often a page fetch that failed without a response,
+// with 429 or with a temporary server error is tried again, waiting
+// exponentially longer with jitter in between. The default is 2.
+func WithRetries(n int) Option {
+	return func(c *Crawler) {
+		c.retries = n
+	}
+}
+
+// WithSitemap enables or disables seeding the crawl from sitemaps.
+func WithSitemap(enabled bool) Option {
+	return func(c *Crawler) {
+		c.useSitemap = enabled
+	}
+}
+
+// WithPageCallback registers fn to be called for every crawled page. It is
+// called from the worker goroutines and must be safe for concurrent use.
+func WithPageCallback(fn func(PageData)) Option {
+	return func(c *Crawler) {
+		c.onPage = fn
+	}
+}
+
+// WithLogger sets the logger for every fetched page and for problems that
+// don't stop the crawl, such as an unavailable sitemap. The default is
+// slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Crawler) {
+		c.logger = logger
+	}
+}
+
+// WithMetrics records the fetches, requests and downloaded bytes of the
+// crawl in m, and adds its queue and workers to the gauges while it runs.
+func WithMetrics(m *Metrics) Option {
+	return func(c *Crawler) {
+		c.metrics = m
+	}
+}
+
+// WithAllowSubdomains also crawls subdomains of the base URL's host.
+func WithAllowSubdomains(allow bool) Option {
+	return func(c *Crawler) {
+		c.allowSubdomains = allow
+	}
+}
+
+// WithSameDomain also crawls the other hosts of the base URL's
+// registrable domain, e.g. example.com and shop.example.com from
+// www.example.com.
+func WithSameDomain(enabled bool) Option {
+	return func(c *Crawler) {
+		c.sameDomain = enabled
+	}
+}
+
+// WithAllowedHosts also crawls the given hosts. "*.example.com" allows
+// every subdomain of example.com.
+func WithAllowedHosts(hosts ...string) Option {
+	return func(c *Crawler) {
+		c.allowedHosts = append(c.allowedHosts, hosts...)
+	}
+}
+
+// WithPathPrefix restricts the crawl to URLs whose path starts with
+// prefix, e.g. "/docs/".
+func WithPathPrefix(prefix string) Option {
+	return func(c *Crawler) {
+		c.pathPrefix = prefix
+	}
+}
+
+// WithInclude restricts the crawl to URLs matching at least one pattern.
+func WithInclude(patterns ...*regexp.Regexp) Option {
+	return func(c *Crawler) {
+		c.include = append(c.include, patterns...)
+	}
+}
+
+// WithExclude skips URLs matching any of the patterns.
+func WithExclude(patterns ...*regexp.Regexp) Option {
+	return func(c *Crawler) {
+		c.exclude = append(c.exclude, patterns...)
+	}
+}
+
+// WithNormalizer sets how URLs are mapped to page identities.
+func WithNormalizer(normalizer *Normalizer) Option {
+	return func(c *Crawler) {
+		c.normalizer = normalizer
+	}
+}
+
+// WithExtractors adds extractors that run after the built-in ones on every
+// crawled page.
+func WithExtractors(extractors ...Extractor) Option {
+	return func(c *Crawler) {
+		c.extractors = append(c.extractors, extractors...)
+	}
+}
+
+// WithAssetCheck checks the images, scripts, stylesheets and other assets
+// of the crawled pages after the crawl, with concurrency parallel requests.
+func WithAssetCheck(concurrency int) Option {
+	return func(c *Crawler) {
+		c.assetConcurrency = concurrency
+	}
+}
+
+// WithExternalLinkCheck requests the out-of-scope links of the crawled
+// pages once after the crawl, with concurrency parallel requests, to find
+// broken ones. They are not crawled.
+func WithExternalLinkCheck(concurrency int) Option {
+	return func(c *Crawler) {
+		c.externalConcurrency = concurrency
+	}
+}
+
+// WithMaxAssetSize sets the size in bytes above which an asset is flagged
+// as oversized, 0 for no limit. The default is 1 MiB.
+func WithMaxAssetSize(size int64) Option {
+	return func(c *Crawler) {
+		c.maxAssetSize = size
+	}
+}
+
+// WithDuplicateThreshold sets the SimHash similarity, from 0 to 1, from
+// which two pages count as near-duplicates. The default is 0.95.
+func WithDuplicateThreshold(threshold float64) Option {
+	return func(c *Crawler) {
+		c.duplicateThreshold = threshold
+	}
+}
+
+// WithCheckpoint saves the crawl state to path every interval and when the
+// crawl ends or is cancelled. An interval of 0 only saves at the end.
+func WithCheckpoint(path string, interval time.Duration) Option {
+	return func(c *Crawler) {
+		c.checkpointPath = path
+		c.checkpointInterval = interval
+	}
+}
+
+// WithResume continues the crawl saved in the checkpoint file instead of
+// starting from the base URL.
+func WithResume(resume bool) Option {
+	return func(c *Crawler) {
+		c.resume = resume
+	}
+}
+
+// WithCache keeps page responses in dir and revalidates them with
+// conditional requests on later crawls, see FetchResult.Change.
+func WithCache(dir string) Option {
+	return func(c *Crawler) {
+		c.cacheDir = dir
+	}
+}
+
+// WithWARC archives every page request and response in a WARC 1.1 file
+// at path, see FromWARC. A resumed crawl appends to the file.
+func WithWARC(path string) Option {
+	return func(c *Crawler) {
+		c.warcPath = path
+	}
+}
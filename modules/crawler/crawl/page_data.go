@@ -0,0 +1,56 @@
+package crawl
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+type PageData struct {
+	URL             string              `json:"url"`
+	Title           string              `json:"title"`
+	MetaDescription string              `json:"meta_description"`
+	MetaRobots      string              `json:"meta_robots"`
+	H1              string              `json:"h1"`
+	FirstParagraph  string              `json:"first_paragraph"`
+	Headings        []Heading           `json:"headings,omitempty"`
+	WordCount       int                 `json:"word_count"`
+	ContentHash     string              `json:"content_hash,omitempty"` // SHA-256 of the normalized main text
+	SimHash         string              `json:"simhash,omitempty"`      // 64-bit SimHash of the main text, in hex
+	OutgoingLinks   []string            `json:"outgoing_links"`
+	ImageURLs       []string            `json:"image_urls"`
+	Links           []Link              `json:"links,omitempty"` // every link with its kind, anchors and assets
+	Canonical       string              `json:"canonical,omitempty"`
+	Alternates      []string            `json:"alternates,omitempty"` // other URLs that served this page
+	Hreflang        []Hreflang          `json:"hreflang,omitempty"`
+	OpenGraph       map[string]string   `json:"open_graph,omitempty"`   // og:* properties
+	TwitterCard     map[string]string   `json:"twitter_card,omitempty"` // twitter:* names
+	JSONLD          []json.RawMessage   `json:"json_ld,omitempty"`
+	Extra           map[string][]string `json:"extra,omitempty"`   // values of custom extractors, by name
+	Metrics         *PageMetrics        `json:"metrics,omitempty"` // place in the link graph, set by the report
+}
+
+// Heading is one entry of the h1-h6 outline.
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+}
+
+// Hreflang is a <link rel="alternate" hreflang> translation of the page.
+type Hreflang struct {
+	Lang string `json:"lang"`
+	URL  string `json:"url"`
+}
+
+func metaWithPrefix(meta map[string]string, prefix string) map[string]string {
+	var matching map[string]string
+	for key, value := range meta {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if matching == nil {
+			matching = make(map[string]string)
+		}
+		matching[key] = value
+	}
+	return matching
+}
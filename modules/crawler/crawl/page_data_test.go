@@ -0,0 +1,153 @@
+package crawl
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractPageData(t *testing.T) {
+	inputURL := "https://blog.domain"
+	inputBody := `<html><body>
+        <h1>Test Title</h1>
+        <p>This is the first paragraph.</p>
+        <a href="/link1">Link 1</a>
+        <img src="/image1.jpg" alt="Image 1">
+    </body></html>`
+
+	actual := extractPageData(inputBody, inputURL, DefaultExtractors())
+
+	expected := PageData{
+		URL:            "https://blog.domain",
+		H1:             "Test Title",
+		FirstParagraph: "This is the first paragraph.",
+		Headings:       []Heading{{Level: 1, Text: "Test Title"}},
+		WordCount:      9,
+		ContentHash:    "18f7681886e0632b344adee997930361833a255c40bde73542d3bdc40d213c64",
+		SimHash:        "5f0728d26908f2dc",
+		OutgoingLinks:  []string{"https://blog.domain/link1"},
+		ImageURLs:      []string{"https://blog.domain/image1.jpg"},
+		Links: []Link{
+			{URL: "https://blog.domain/link1", Kind: LinkAnchor, Text: "Link 1"},
+			{URL: "https://blog.domain/image1.jpg", Kind: LinkImage, Text: "Image 1"},
+		},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestExtractPageDataMetadata(t *testing.T) {
+	inputURL := "https://blog.domain/post"
+	inputBody := `<html><head>
+        <title> Post Title </title>
+        <meta name="description" content="A short summary.">
+        <meta name="robots" content="noindex, follow">
+        <meta property="og:title" content="OG Title">
+        <meta property="og:image" content="https://blog.domain/og.png">
+        <meta name="twitter:card" content="summary">
+        <link rel="alternate" hreflang="de" href="/de/post">
+        <script type="application/ld+json">{ "@type": "Article",
+            "headline": "Post" }</script>
+        <script type="application/ld+json">{ broken</script>
+    </head><body>
+        <h1>Post</h1>
+        <h2>Part <em>one</em></h2>
+        <h3>Detail</h3>
+        <script>var notCounted = "a b c";</script>
+    </body></html>`
+
+	actual := extractPageData(inputBody, inputURL, DefaultExtractors())
+
+	if actual.Title != "Post Title" {
+		t.Errorf("expected title %q, got %q", "Post Title", actual.Title)
+	}
+	if actual.MetaDescription != "A short summary." || actual.MetaRobots != "noindex, follow" {
+		t.Errorf("unexpected meta tags: %q, %q", actual.MetaDescription, actual.MetaRobots)
+	}
+	expectedOpenGraph := map[string]string{"og:title": "OG Title", "og:image": "https://blog.domain/og.png"}
+	if !reflect.DeepEqual(actual.OpenGraph, expectedOpenGraph) {
+		t.Errorf("expected %v, got %v", expectedOpenGraph, actual.OpenGraph)
+	}
+	if !reflect.DeepEqual(actual.TwitterCard, map[string]string{"twitter:card": "summary"}) {
+		t.Errorf("unexpected twitter card: %v", actual.TwitterCard)
+	}
+	expectedHreflang := []Hreflang{{Lang: "de", URL: "https://blog.domain/de/post"}}
+	if !reflect.DeepEqual(actual.Hreflang, expectedHreflang) {
+		t.Errorf("expected %v, got %v", expectedHreflang, actual.Hreflang)
+	}
+	expectedHeadings := []Heading{{Level: 1, Text: "Post"}, {Level: 2, Text: "Part one"}, {Level: 3, Text: "Detail"}}
+	if !reflect.DeepEqual(actual.Headings, expectedHeadings) {
+		t.Errorf("expected %v, got %v", expectedHeadings, actual.Headings)
+	}
+	if actual.WordCount != 4 {
+		t.Errorf("expected 4 words, got %d", actual.WordCount)
+	}
+	expectedJSONLD := []json.RawMessage{json.RawMessage(`{"@type":"Article","headline":"Post"}`)}
+	if !reflect.DeepEqual(actual.JSONLD, expectedJSONLD) {
+		t.Errorf("expected %s, got %s", expectedJSONLD, actual.JSONLD)
+	}
+}
+
+func TestExtractPageDataBaseHref(t *testing.T) {
+	inputURL := "https://blog.domain/post"
+	inputBody := `<html><head>
+        <base href="https://blog.domain/en/">
+        <link rel="canonical" href="post">
+        <link rel="alternate" hreflang="de" href="../de/post">
+    </head><body>
+        <a href="about">About</a>
+    </body></html>`
+
+	actual := extractPageData(inputBody, inputURL, DefaultExtractors())
+
+	if actual.Canonical != "https://blog.domain/en/post" {
+		t.Errorf("expected canonical %q, got %q", "https://blog.domain/en/post", actual.Canonical)
+	}
+	expectedHreflang := []Hreflang{{Lang: "de", URL: "https://blog.domain/de/post"}}
+	if !reflect.DeepEqual(actual.Hreflang, expectedHreflang) {
+		t.Errorf("expected %v, got %v", expectedHreflang, actual.Hreflang)
+	}
+	if !reflect.DeepEqual(actual.OutgoingLinks, []string{"https://blog.domain/en/about"}) {
+		t.Errorf("expected %v, got %v", []string{"https://blog.domain/en/about"}, actual.OutgoingLinks)
+	}
+}
+
+func TestExtractPageDataCustomExtractors(t *testing.T) {
+	inputURL := "https://blog.domain/post"
+	inputBody := `<html><body>
+        <h1>Post</h1>
+        <span class="author">Ada  Lovelace</span>
+        <a class="tag" href="/tags/go">go</a>
+        <a class="tag" href="/tags/html">html</a>
+        <p>First paragraph.</p>
+    </body></html>`
+
+	extractors := append(DefaultExtractors(),
+		SelectorExtractor{Name: "author", Selector: ".author"},
+		SelectorExtractor{Name: "tags", Selector: "a.tag", All: true},
+		SelectorExtractor{Name: "tag_links", Selector: "a.tag", Attr: "href", All: true},
+		SelectorExtractor{Name: "missing", Selector: ".nothing"},
+		ExtractorFunc(func(page *Page, data *PageData) {
+			data.FirstParagraph = strings.ToUpper(data.FirstParagraph)
+		}),
+	)
+	actual := extractPageData(inputBody, inputURL, extractors)
+
+	expectedExtra := map[string][]string{
+		"author":    {"Ada Lovelace"},
+		"tags":      {"go", "html"},
+		"tag_links": {"/tags/go", "/tags/html"},
+	}
+	if !reflect.DeepEqual(actual.Extra, expectedExtra) {
+		t.Errorf("expected %v, got %v", expectedExtra, actual.Extra)
+	}
+	if actual.H1 != "Post" || actual.WordCount != 7 {
+		t.Errorf("expected built-in fields to be extracted, got %+v", actual)
+	}
+	if actual.FirstParagraph != "FIRST PARAGRAPH." {
+		t.Errorf("expected custom extractor to run last, got %q", actual.FirstParagraph)
+	}
+}
@@ -0,0 +1,196 @@
+package crawl
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+func parseHTML(htmlBody string) (*goquery.Document, error) {
+	return goquery.NewDocumentFromReader(strings.NewReader(htmlBody))
+}
+
+func getH1FromHTML(doc *goquery.Document) string {
+	return doc.Find("h1").First().Text()
+}
+
+func getFirstParagraphFromHTML(doc *goquery.Document) string {
+	mainSelection := doc.Find("main")
+	foundMain := mainSelection.Length() > 0
+	if foundMain {
+		return mainSelection.Find("p").First().Text()
+	} else {
+		return doc.Find("p").First().Text()
+	}
+}
+
+// getBaseURL returns the URL the relative links of the document resolve
+// against: its first <base href>, or else the page URL. It is nil if the
+// page URL is invalid.
+func getBaseURL(doc *goquery.Document, pageURL string) *url.URL {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	if href, exist := doc.Find("base[href]").First().Attr("href"); exist {
+		if baseHref, err := base.Parse(strings.TrimSpace(href)); err == nil {
+			return baseHref
+		}
+	}
+	return base
+}
+
+func getCanonicalFromHTML(doc *goquery.Document, base *url.URL) string {
+	val, exist := doc.Find(`link[rel~="canonical"][href]`).First().Attr("href")
+	if !exist || base == nil {
+		return ""
+	}
+
+	canonicalURL, err := base.Parse(strings.TrimSpace(val))
+	if err != nil {
+		return ""
+	}
+	return canonicalURL.String()
+}
+
+func getTitleFromHTML(doc *goquery.Document) string {
+	return strings.TrimSpace(doc.Find("title").First().Text())
+}
+
+// getMetaFromHTML returns the content of <meta name=...> tags, keyed by
+// lowercase name, and of OpenGraph style <meta property=...> tags.
+func getMetaFromHTML(doc *goquery.Document) map[string]string {
+	meta := make(map[string]string)
+	doc.Find("meta[content]").Each(func(_ int, item *goquery.Selection) {
+		key, exist := item.Attr("property")
+		if !exist {
+			key, exist = item.Attr("name")
+		}
+		if !exist {
+			return
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if _, found := meta[key]; found {
+			// first one wins, like in browsers
+			return
+		}
+		content, _ := item.Attr("content")
+		meta[key] = strings.TrimSpace(content)
+	})
+	return meta
+}
+
+func getHreflangFromHTML(doc *goquery.Document, base *url.URL) []Hreflang {
+	if base == nil {
+		return nil
+	}
+
+	var alternates []Hreflang
+	doc.Find(`link[rel~="alternate"][hreflang][href]`).Each(func(_ int, item *goquery.Selection) {
+		lang, _ := item.Attr("hreflang")
+		href, _ := item.Attr("href")
+		hrefURL, err := base.Parse(strings.TrimSpace(href))
+		if err != nil {
+			return
+		}
+		alternates = append(alternates, Hreflang{Lang: strings.TrimSpace(lang), URL: hrefURL.String()})
+	})
+	return alternates
+}
+
+func getHeadingsFromHTML(doc *goquery.Document) []Heading {
+	var headings []Heading
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(_ int, item *goquery.Selection) {
+		level := int(goquery.NodeName(item)[1] - '0')
+		text := strings.Join(strings.Fields(item.Text()), " ")
+		headings = append(headings, Heading{Level: level, Text: text})
+	})
+	return headings
+}
+
+// getWordCountFromHTML counts the words of the visible body text.
+func getWordCountFromHTML(doc *goquery.Document) int {
+	// work on a copy, the document is shared with the other extractors
+	body := doc.Find("body").Clone()
+	body.Find("script, style, noscript, template").Remove()
+	return len(strings.Fields(body.Text()))
+}
+
+// getMainTextFromHTML returns the words of the page's main content: its
+// <main> element, or its only <article>, or else the body without the
+// navigation, header, footer, sidebars and forms around the content.
+func getMainTextFromHTML(doc *goquery.Document) string {
+	content := doc.Find(`main, [role="main"]`).First()
+	if content.Length() == 0 {
+		if articles := doc.Find("article"); articles.Length() == 1 {
+			content = articles
+		}
+	}
+	if content.Length() == 0 {
+		content = doc.Find("body")
+	}
+	// work on a copy, the document is shared with the other extractors
+	content = content.Clone()
+	content.Find("script, style, noscript, template, nav, header, footer, aside, form").Remove()
+
+	// inline elements join their text, blocks keep the words of adjacent
+	// ones apart
+	var text strings.Builder
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		block := node.Type == html.ElementNode && blockElements[node.DataAtom]
+		if block {
+			text.WriteByte(' ')
+		}
+		if node.Type == html.TextNode {
+			text.WriteString(node.Data)
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		if block {
+			text.WriteByte(' ')
+		}
+	}
+	for _, node := range content.Nodes {
+		walk(node)
+	}
+	return strings.Join(strings.Fields(text.String()), " ")
+}
+
+// blockElements are the elements whose text getMainTextFromHTML keeps
+// apart from the text around them.
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true,
+	atom.Br: true, atom.Caption: true, atom.Dd: true, atom.Details: true,
+	atom.Dialog: true, atom.Div: true, atom.Dl: true, atom.Dt: true,
+	atom.Fieldset: true, atom.Figcaption: true, atom.Figure: true, atom.Footer: true,
+	atom.Form: true, atom.H1: true, atom.H2: true, atom.H3: true,
+	atom.H4: true, atom.H5: true, atom.H6: true, atom.Header: true,
+	atom.Hgroup: true, atom.Hr: true, atom.Li: true, atom.Main: true,
+	atom.Nav: true, atom.Ol: true, atom.Option: true, atom.P: true,
+	atom.Pre: true, atom.Section: true, atom.Summary: true, atom.Table: true,
+	atom.Td: true, atom.Th: true, atom.Tr: true, atom.Ul: true,
+}
+
+// getJSONLDFromHTML returns the valid JSON-LD blocks of the page.
+func getJSONLDFromHTML(doc *goquery.Document) []json.RawMessage {
+	var blocks []json.RawMessage
+	doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, item *goquery.Selection) {
+		block := strings.TrimSpace(item.Text())
+		if !json.Valid([]byte(block)) {
+			return
+		}
+		var compacted bytes.Buffer
+		if err := json.Compact(&compacted, []byte(block)); err != nil {
+			return
+		}
+		blocks = append(blocks, json.RawMessage(compacted.Bytes()))
+	})
+	return blocks
+}
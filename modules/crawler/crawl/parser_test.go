@@ -1,14 +1,16 @@
-package main
+package crawl
 
 import (
 	"net/url"
 	"reflect"
 	"testing"
+
+	"github.com/PuerkitoBio/goquery"
 )
 
 func TestGetH1FromHTMLBasic(t *testing.T) {
 	inputBody := "<html><body><h1>Test Title</h1></body></html>"
-	actual := getH1FromHTML(inputBody)
+	actual := getH1FromHTML(mustParseHTML(t, inputBody))
 	expected := "Test Title"
 
 	if actual != expected {
@@ -23,7 +25,7 @@ func TestGetFirstParagraphFromHTMLMainPriority(t *testing.T) {
 			<p>Main paragraph.</p>
 		</main>
 	</body></html>`
-	actual := getFirstParagraphFromHTML(inputBody)
+	actual := getFirstParagraphFromHTML(mustParseHTML(t, inputBody))
 	expected := "Main paragraph."
 
 	if actual != expected {
@@ -35,7 +37,7 @@ func TestGetEmptyFirstParagraphFromHTML(t *testing.T) {
 	inputBody := `<html><body>
 		<div>No paragraphs here!</div>
 	</body></html>`
-	actual := getFirstParagraphFromHTML(inputBody)
+	actual := getFirstParagraphFromHTML(mustParseHTML(t, inputBody))
 	expected := ""
 
 	if actual != expected {
@@ -81,11 +83,8 @@ func TestGetURLsFromHTMLAbsolute(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			actual, err := getURLsFromHTML(tc.inputBody, inputURL)
-			if err != nil {
-				t.Fatalf("unexpected error: %v", err)
-			}
-
+			doc := mustParseHTML(t, tc.inputBody)
+			actual := linkURLs(getLinksFromHTML(doc, getBaseURL(doc, inputURL)), LinkAnchor)
 			if !reflect.DeepEqual(actual, tc.expected) {
 				t.Errorf("expected %v, got %v", tc.expected, actual)
 			}
@@ -126,14 +125,97 @@ func TestGetImagesFromHTMLRelative(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			actual, err := getImagesFromHTML(tc.input, inputURL)
-			if err != nil {
-				t.Fatalf("unexpected error: %v", err)
-			}
-
+			doc := mustParseHTML(t, tc.input)
+			actual := linkURLs(getLinksFromHTML(doc, getBaseURL(doc, inputURL)), LinkImage)
 			if !reflect.DeepEqual(actual, tc.expected) {
 				t.Errorf("expected %v, got %v", tc.expected, actual)
 			}
 		})
 	}
 }
+
+func TestGetCanonicalFromHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "relative canonical",
+			input:    `<html><head><link rel="canonical" href="/article"></head></html>`,
+			expected: "https://blog.web.dev/article",
+		},
+		{
+			name:     "absolute canonical",
+			input:    `<html><head><link rel="canonical" href="https://blog.web.dev/article"></head></html>`,
+			expected: "https://blog.web.dev/article",
+		},
+		{
+			name:     "canonical relative to base href",
+			input:    `<html><head><base href="/en/"><link rel="canonical" href="article"></head></html>`,
+			expected: "https://blog.web.dev/en/article",
+		},
+		{
+			name:     "no canonical",
+			input:    `<html><head><link rel="stylesheet" href="/style.css"></head></html>`,
+			expected: "",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			doc := mustParseHTML(t, tc.input)
+			actual := getCanonicalFromHTML(doc, getBaseURL(doc, "https://blog.web.dev/print"))
+			if actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetMainTextFromHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "main element",
+			input:    `<body><nav>Home</nav><main><h1>Title</h1><p>Some <b>bold</b>text.</p><p>More.</p></main><footer>(c)</footer></body>`,
+			expected: "Title Some boldtext. More.",
+		},
+		{
+			name:     "inline and block elements",
+			input:    `<body><p>un<em>believ</em>able</p><ul><li>One</li><li>Two</li></ul><p>Line<br>break</p><table><tr><td>A</td><td>B</td></tr></table></body>`,
+			expected: "unbelievable One Two Line break A B",
+		},
+		{
+			name:     "single article",
+			input:    `<body><header>Site</header><article><p>Story.</p></article><aside>Related</aside></body>`,
+			expected: "Story.",
+		},
+		{
+			name:     "body without chrome",
+			input:    `<body><header>Site</header><article>One</article><article>Two</article><script>x()</script><form>Search</form></body>`,
+			expected: "One Two",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := getMainTextFromHTML(mustParseHTML(t, tc.input))
+			if actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func mustParseHTML(t *testing.T, htmlBody string) *goquery.Document {
+	t.Helper()
+	doc, err := parseHTML(htmlBody)
+	if err != nil {
+		t.Fatalf("couldn't parse HTML: %v", err)
+	}
+	return doc
+}
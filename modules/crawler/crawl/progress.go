@@ -0,0 +1,71 @@
+package crawl
+
+import "time"
+
+// Progress is a snapshot of the counters of a crawl. It can be taken while
+// the crawl runs.
+type Progress struct {
+	Pages    int           `json:"pages"`     // crawled pages
+	MaxPages int           `json:"max_pages"` // see WithMaxPages
+	Fetched  int           `json:"fetched"`   // fetched URLs, failed ones included
+	Failed   int           `json:"failed"`    // fetches with an error or a 4xx/5xx status
+	Queued   int           `json:"queued"`    // URLs waiting to be crawled
+	InFlight int           `json:"in_flight"` // URLs being crawled
+	Skipped  int           `json:"skipped"`   // URLs blocked by robots.txt
+	Requests int64         `json:"requests"`  // page, asset and external link requests, retries included
+	Bytes    int64         `json:"bytes"`     // response bytes downloaded
+	Elapsed  time.Duration `json:"elapsed_ns"`
+}
+
+// RequestsPerSecond is the average request rate so far.
+func (p Progress) RequestsPerSecond() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Requests) / p.Elapsed.Seconds()
+}
+
+// ETA estimates the time left until MaxPages pages are crawled at the page
+// rate so far, 0 if there is no estimate yet or nothing is left to crawl.
+// The crawl ends earlier if it runs out of pages.
+func (p Progress) ETA() time.Duration {
+	if p.Pages == 0 || p.Pages >= p.MaxPages || p.Queued+p.InFlight == 0 {
+		return 0
+	}
+	perPage := p.Elapsed / time.Duration(p.Pages)
+	return perPage * time.Duration(p.MaxPages-p.Pages)
+}
+
+// Progress returns the current counters of the crawl.
+func (c *Crawler) Progress() Progress {
+	p := Progress{
+		MaxPages: c.maxPages,
+		Requests: c.requests.Load(),
+		Bytes:    c.received.Load(),
+	}
+	p.Queued, p.InFlight = c.frontier.counts()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p.Elapsed = c.elapsed()
+	p.Pages = len(c.pages)
+	p.Fetched = len(c.fetches)
+	p.Skipped = len(c.skipped)
+	for _, result := range c.fetches {
+		if result.Broken() {
+			p.Failed++
+		}
+	}
+	return p
+}
+
+// elapsed is the run time of the crawl so far. c.mu must be held.
+func (c *Crawler) elapsed() time.Duration {
+	switch {
+	case c.started.IsZero():
+		return 0
+	case c.finished.IsZero():
+		return time.Since(c.started)
+	}
+	return c.finished.Sub(c.started)
+}
@@ -0,0 +1,221 @@
+package crawl
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultMaxInFlight = 2
+	defaultRetries     = 2
+
+	// retryBaseDelay is the backoff before the first retry; it doubles for
+	// every further attempt.
+	retryBaseDelay = 500 * time.Millisecond
+
+	// after a 429 or 503 the time between requests to the host doubles, up
+	// to maxSlowdown times the normal interval, and every other response
+	// brings it back down a bit
+	maxSlowdown      = 32
+	slowdownRecovery = 0.9
+
+	// minSlowdownInterval is the interval a slowed down host is paced from
+	// when the normal one is shorter, so that a host without a delay backs
+	// off too.
+	minSlowdownInterval = 100 * time.Millisecond
+
+	// maxRetryAfter caps how long a Retry-After header can pause a host.
+	maxRetryAfter = 5 * time.Minute
+)
+
+// hostLimiter paces the requests per host with a token bucket holding a
+// single token, refilled every interval, and caps how many requests to a
+// host are in flight at once.
+type hostLimiter struct {
+	mu          sync.Mutex
+	maxInFlight int // 0 for no limit
+	hosts       map[string]*hostState
+}
+
+type hostState struct {
+	next     time.Time     // when the next token is available
+	slowdown float64       // multiplies the interval, raised by 429 and 503
+	slots    chan struct{} // in-flight requests, nil for no limit
+}
+
+func newHostLimiter(maxInFlight int) *hostLimiter {
+	return &hostLimiter{maxInFlight: maxInFlight, hosts: make(map[string]*hostState)}
+}
+
+func (l *hostLimiter) host(host string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	state, found := l.hosts[host]
+	if !found {
+		state = &hostState{slowdown: 1}
+		if l.maxInFlight > 0 {
+			state.slots = make(chan struct{}, l.maxInFlight)
+		}
+		l.hosts[host] = state
+	}
+	return state
+}
+
+// wait blocks until a request to host may start, at least interval after
+// the previous one. The request holds one of the host's in-flight slots
+// until release is called.
+func (l *hostLimiter) wait(ctx context.Context, host string, interval time.Duration) (release func(), err error) {
+	state := l.host(host)
+	if state.slots != nil {
+		select {
+		case state.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release = sync.OnceFunc(func() {
+		if state.slots != nil {
+			<-state.slots
+		}
+	})
+
+	l.mu.Lock()
+	now := time.Now()
+	start := state.next
+	if start.Before(now) {
+		start = now
+	}
+	state.next = start.Add(state.pace(interval))
+	l.mu.Unlock()
+
+	if wait := start.Sub(now); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// pace is the time between requests to the host at the normal interval,
+// stretched by the slowdown. l.mu must be held.
+func (s *hostState) pace(interval time.Duration) time.Duration {
+	if s.slowdown > 1 {
+		interval = max(interval, minSlowdownInterval)
+	}
+	return time.Duration(float64(interval) * s.slowdown)
+}
+
+// update adapts the pace of host to a response: 429 Too Many Requests and
+// 503 Service Unavailable slow it down and a Retry-After header holds back
+// the next request, any other response lets it speed up again.
+func (l *hostLimiter) update(host string, response *http.Response) {
+	state := l.host(host)
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if response.StatusCode != http.StatusTooManyRequests && response.StatusCode != http.StatusServiceUnavailable {
+		state.slowdown = max(1, state.slowdown*slowdownRecovery)
+		return
+	}
+	state.slowdown = min(maxSlowdown, state.slowdown*2)
+	if retryAfter, ok := parseRetryAfter(response.Header.Get("Retry-After"), now); ok {
+		if resume := now.Add(min(retryAfter, maxRetryAfter)); resume.After(state.next) {
+			state.next = resume
+		}
+	}
+}
+
+// parseRetryAfter reads a Retry-After header, either in seconds or as an
+// HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(0, date.Sub(now)), true
+}
+
+// send does request with client once the limiter lets it through, at
+// least interval after the previous request to the host, and feeds the
+// response back into the limiter. The in-flight slot is held until the
+// response body is closed.
+func (c *Crawler) send(client *http.Client, request *http.Request, interval time.Duration) (*http.Response, error) {
+	host := request.URL.Host
+	release, err := c.limiter.wait(request.Context(), host, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	c.limiter.update(host, response)
+	c.requests.Add(1)
+	c.metrics.addRequest()
+	response.Body = &releasingBody{ReadCloser: response.Body, release: release, received: c.received, metrics: c.metrics}
+	return response, nil
+}
+
+// releasingBody counts the bytes read into received and the metrics, and
+// calls release when the body is closed.
+type releasingBody struct {
+	io.ReadCloser
+	release  func()
+	received *atomic.Int64
+	metrics  *Metrics
+}
+
+func (b *releasingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.received.Add(int64(n))
+	b.metrics.addBytes(n)
+	return n, err
+}
+
+func (b *releasingBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
+
+// retryable reports whether a failed fetch with status is worth another
+// try: no response at all, 429 or a temporary server error.
+func retryable(status int) bool {
+	switch status {
+	case 0, http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryBackoff returns the wait before retry number attempt, counting from
+// 0: an exponentially growing delay of which a random half is jitter.
+func retryBackoff(attempt int) time.Duration {
+	backoff := retryBaseDelay << attempt
+	return backoff/2 + rand.N(backoff/2+1)
+}
@@ -0,0 +1,238 @@
+package crawl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"seconds", "120", 2 * time.Minute, true},
+		{"zero", "0", 0, true},
+		{"date", "Wed, 01 May 2024 12:00:30 GMT", 30 * time.Second, true},
+		{"date in the past", "Wed, 01 May 2024 11:00:00 GMT", 0, true},
+		{"empty", "", 0, false},
+		{"negative", "-5", 0, false},
+		{"garbage", "soon", 0, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, ok := parseRetryAfter(tc.value, now)
+			if ok != tc.ok || actual != tc.expected {
+				t.Errorf("expected %v %v, got %v %v", tc.expected, tc.ok, actual, ok)
+			}
+		})
+	}
+}
+
+func TestHostLimiterPacesRequests(t *testing.T) {
+	limiter := newHostLimiter(0)
+	start := time.Now()
+	for range 3 {
+		release, err := limiter.wait(context.Background(), "example.com", 20*time.Millisecond)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("expected 3 requests to take at least 40ms, got %v", elapsed)
+	}
+
+	// other hosts have their own bucket
+	start = time.Now()
+	release, err := limiter.wait(context.Background(), "other.example.com", 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	release()
+	if elapsed := time.Since(start); elapsed >= 20*time.Millisecond {
+		t.Errorf("expected the first request to another host to start at once, got %v", elapsed)
+	}
+}
+
+func TestHostLimiterMaxInFlight(t *testing.T) {
+	limiter := newHostLimiter(1)
+	release, err := limiter.wait(context.Background(), "example.com", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.wait(ctx, "example.com", 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the second request to wait for the first, got %v", err)
+	}
+
+	release()
+	release() // releasing twice frees a single slot
+	second, err := limiter.wait(context.Background(), "example.com", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.wait(ctx, "example.com", 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a single slot, got %v", err)
+	}
+	second()
+}
+
+func TestHostLimiterSlowsDown(t *testing.T) {
+	limiter := newHostLimiter(0)
+	response := func(status int, retryAfter string) *http.Response {
+		header := http.Header{}
+		if retryAfter != "" {
+			header.Set("Retry-After", retryAfter)
+		}
+		return &http.Response{StatusCode: status, Header: header}
+	}
+
+	limiter.update("example.com", response(http.StatusTooManyRequests, ""))
+	limiter.update("example.com", response(http.StatusServiceUnavailable, "60"))
+	state := limiter.host("example.com")
+	if state.slowdown != 4 {
+		t.Errorf("expected slowdown 4, got %v", state.slowdown)
+	}
+	if wait := time.Until(state.next); wait < 59*time.Second || wait > time.Minute {
+		t.Errorf("expected the next request in about a minute, got %v", wait)
+	}
+
+	for range 100 {
+		limiter.update("example.com", response(http.StatusOK, ""))
+	}
+	if state.slowdown != 1 {
+		t.Errorf("expected slowdown 1 after successful responses, got %v", state.slowdown)
+	}
+}
+
+func TestHostLimiterSlowsDownWithoutInterval(t *testing.T) {
+	limiter := newHostLimiter(0)
+	for range 2 {
+		release, err := limiter.wait(context.Background(), "example.com", 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		release()
+	}
+	state := limiter.host("example.com")
+	if wait := time.Until(state.next); wait > 0 {
+		t.Errorf("expected no wait without an interval, got %v", wait)
+	}
+
+	// a 429 without Retry-After still paces a host that has no delay
+	limiter.update("example.com", &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}})
+	start := time.Now()
+	for range 2 {
+		release, err := limiter.wait(context.Background(), "example.com", 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 2*minSlowdownInterval {
+		t.Errorf("expected the second request after at least %v, got %v", 2*minSlowdownInterval, elapsed)
+	}
+}
+
+func TestCrawlRetries(t *testing.T) {
+	var flakyRequests atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nCrawl-delay: 0.001\n")
+	})
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><a href="/flaky">flaky</a><a href="/down">down</a><a href="/missing">missing</a></body></html>`)
+	})
+	mux.HandleFunc("/flaky", func(w http.ResponseWriter, r *http.Request) {
+		if flakyRequests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body><h1>Back</h1></body></html>")
+	})
+	mux.HandleFunc("/down", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	c := newTestCrawler(t, server.URL+"/", WithRetries(1))
+
+	fetches := make(map[string]FetchResult)
+	for _, result := range c.Fetches() {
+		fetches[strings.TrimPrefix(result.URL, server.URL)] = result
+	}
+	tests := []struct {
+		path    string
+		status  int
+		retries int
+	}{
+		{"/", http.StatusOK, 0},
+		{"/flaky", http.StatusOK, 1},
+		{"/down", http.StatusInternalServerError, 1},
+		{"/missing", http.StatusNotFound, 0},
+	}
+	for _, tc := range tests {
+		result := fetches[tc.path]
+		if result.StatusCode != tc.status || result.Retries != tc.retries {
+			t.Errorf("expected %s to end with %d after %d retries, got %+v", tc.path, tc.status, tc.retries, result)
+		}
+	}
+	if _, found := c.Pages()[strings.TrimPrefix(server.URL, "http://")+"/flaky"]; !found {
+		t.Errorf("expected /flaky to be crawled after the retry, got %v", crawledPaths(c))
+	}
+}
+
+func TestCrawlMaxInFlight(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\n")
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			highest := maxInFlight.Load()
+			if current <= highest || maxInFlight.CompareAndSwap(highest, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		w.Header().Set("Content-Type", "text/html")
+		var links strings.Builder
+		for i := range 10 {
+			fmt.Fprintf(&links, `<a href="/page%d">page %d</a>`, i, i)
+		}
+		fmt.Fprintf(w, "<html><body>%s</body></html>", links.String())
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	c := newTestCrawler(t, server.URL+"/", WithConcurrency(5), WithRateLimit(0, 2))
+
+	if len(c.Pages()) != 11 {
+		t.Errorf("expected 11 pages, got %v", crawledPaths(c))
+	}
+	if highest := maxInFlight.Load(); highest > 2 {
+		t.Errorf("expected at most 2 requests in flight, got %d", highest)
+	}
+}
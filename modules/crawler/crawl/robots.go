@@ -0,0 +1,296 @@
+package crawl
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultUserAgent is sent with every request unless WithUserAgent is used.
+const DefaultUserAgent = "MyCrawler/1.0"
+
+const defaultCrawlDelay = 500 * time.Millisecond
+
+type robotsRule struct {
+	allow bool
+	path  string
+}
+
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// robotsRules is the subset of a robots.txt file that applies to one user agent.
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	sitemaps   []string
+	unreadable error // why robots.txt couldn't be read, the rules then deny everything
+}
+
+func parseRobotsTxt(r io.Reader, agent string) *robotsRules {
+	var groups []*robotsGroup
+	var sitemaps []string
+	var current *robotsGroup
+	lastWasAgent := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// consecutive user-agent lines share the same group
+			if current == nil || !lastWasAgent {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			lastWasAgent = true
+			continue
+		case "allow", "disallow":
+			if current != nil {
+				// an empty disallow matches nothing
+				if value != "" {
+					current.rules = append(current.rules, robotsRule{allow: key == "allow", path: value})
+				}
+			}
+		case "crawl-delay":
+			if current != nil {
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+					current.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		case "sitemap":
+			if value != "" {
+				sitemaps = append(sitemaps, value)
+			}
+		}
+		lastWasAgent = false
+	}
+
+	rules := &robotsRules{sitemaps: sitemaps}
+	if group := matchRobotsGroup(groups, agent); group != nil {
+		rules.rules = group.rules
+		rules.crawlDelay = group.crawlDelay
+	}
+	return rules
+}
+
+// matchRobotsGroup merges the groups naming the agent's product token,
+// compared case-insensitively as RFC 9309 requires, or else the "*"
+// groups. The longest Crawl-delay of the merged groups applies.
+func matchRobotsGroup(groups []*robotsGroup, agent string) *robotsGroup {
+	// product token only, e.g. "MyCrawler" from "MyCrawler/1.0"
+	token, _, _ := strings.Cut(agent, "/")
+
+	var matched, wildcard *robotsGroup
+	for _, group := range groups {
+		if slices.ContainsFunc(group.agents, func(name string) bool { return strings.EqualFold(name, token) }) {
+			matched = mergeRobotsGroup(matched, group)
+		} else if slices.Contains(group.agents, "*") {
+			wildcard = mergeRobotsGroup(wildcard, group)
+		}
+	}
+	if matched != nil {
+		return matched
+	}
+	return wildcard
+}
+
+// mergeRobotsGroup adds the rules of group to merged, which may be nil.
+func mergeRobotsGroup(merged, group *robotsGroup) *robotsGroup {
+	if merged == nil {
+		merged = &robotsGroup{}
+	}
+	merged.rules = append(merged.rules, group.rules...)
+	merged.crawlDelay = max(merged.crawlDelay, group.crawlDelay)
+	return merged
+}
+
+// allowed reports whether the path (with query) may be fetched. The longest
+// matching rule wins and allow wins over disallow on a tie.
+func (r *robotsRules) allowed(path string) bool {
+	if r == nil {
+		return true
+	}
+	if path == "" {
+		path = "/"
+	}
+	if path == "/robots.txt" {
+		return true
+	}
+
+	matchLen := -1
+	allow := true
+	for _, rule := range r.rules {
+		if !robotsPathMatch(rule.path, path) {
+			continue
+		}
+		if len(rule.path) > matchLen || (len(rule.path) == matchLen && rule.allow) {
+			matchLen = len(rule.path)
+			allow = rule.allow
+		}
+	}
+	return allow
+}
+
+// disallows reports whether a Disallow rule forbids the path. Unlike
+// !allowed it is false when robots.txt couldn't be read, so the asset and
+// external link checks still request the URL and record why it fails.
+func (r *robotsRules) disallows(path string) bool {
+	return r != nil && r.unreadable == nil && !r.allowed(path)
+}
+
+// delay returns the Crawl-delay for our user agent or the fallback delay.
+func (r *robotsRules) delay(fallback time.Duration) time.Duration {
+	if r == nil || r.crawlDelay == 0 {
+		return fallback
+	}
+	return r.crawlDelay
+}
+
+// robotsPathMatch matches a robots.txt path pattern supporting "*" and a
+// trailing "$" anchor.
+func robotsPathMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for _, part := range parts[1:] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	if anchored {
+		last := parts[len(parts)-1]
+		if len(parts) == 1 {
+			return rest == ""
+		}
+		return strings.HasSuffix(path, last)
+	}
+	return true
+}
+
+type robotsEntry struct {
+	mu    sync.Mutex
+	rules *robotsRules // nil until fetched
+}
+
+// robotsCache fetches robots.txt once per host.
+type robotsCache struct {
+	mu      sync.Mutex
+	entries map[string]*robotsEntry
+	fetch   func(ctx context.Context, rawURL string) (*robotsRules, error)
+	logger  *slog.Logger
+}
+
+func newRobotsCache(fetch func(ctx context.Context, rawURL string) (*robotsRules, error), logger *slog.Logger) *robotsCache {
+	return &robotsCache{
+		entries: make(map[string]*robotsEntry),
+		fetch:   fetch,
+		logger:  logger,
+	}
+}
+
+// get returns the robots.txt rules of the page's host, fetching them on
+// first use. It only fails when ctx is done; the rules are then left
+// unfetched for the next caller instead of denying the host.
+func (rc *robotsCache) get(ctx context.Context, pageURL *url.URL) (*robotsRules, error) {
+	rc.mu.Lock()
+	entry, found := rc.entries[pageURL.Host]
+	if !found {
+		entry = &robotsEntry{}
+		rc.entries[pageURL.Host] = entry
+	}
+	rc.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.rules != nil {
+		return entry.rules, nil
+	}
+	robotsURL := url.URL{Scheme: pageURL.Scheme, Host: pageURL.Host, Path: "/robots.txt"}
+	rules, err := rc.fetch(ctx, robotsURL.String())
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		rc.logger.Info("robots.txt unavailable, not crawling the host", "host", pageURL.Host, "error", err)
+		// unreachable robots.txt means nothing may be crawled
+		rules = &robotsRules{rules: []robotsRule{{allow: false, path: "/"}}, unreadable: err}
+	}
+	entry.rules = rules
+	return rules, nil
+}
+
+// checkAllowed reads the robots.txt of a URL to be checked, not crawled.
+// It reports false if ctx is done or a Disallow rule forbids the URL, which
+// is then recorded as skipped.
+func (c *Crawler) checkAllowed(ctx context.Context, rawURL string) bool {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	robots, err := c.robots.get(ctx, parsedURL)
+	if err != nil {
+		return false
+	}
+	if robots.disallows(parsedURL.RequestURI()) {
+		c.addSkipped(rawURL)
+		return false
+	}
+	return true
+}
+
+func (c *Crawler) getRobotsTxt(ctx context.Context, rawURL string) (*robotsRules, error) {
+	request, err := c.newRequest(ctx, http.MethodGet, rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode >= 500:
+		return nil, fmt.Errorf("received status code %d", response.StatusCode)
+	case response.StatusCode >= 400:
+		// no robots.txt, everything is allowed
+		return &robotsRules{}, nil
+	}
+
+	// cap at 500 KiB as recommended by RFC 9309
+	return parseRobotsTxt(io.LimitReader(response.Body, 500*1024), c.userAgent), nil
+}
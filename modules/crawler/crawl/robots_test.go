@@ -0,0 +1,183 @@
+package crawl
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRobotsAllowed(t *testing.T) {
+	robotsTxt := `
+User-agent: *
+Disallow: /
+
+User-agent: MyCrawler
+User-agent: OtherBot
+Disallow: /private
+Allow: /private/public
+Disallow: /*.pdf$
+Crawl-delay: 2
+
+Sitemap: https://blog.test.dev/sitemap.xml
+`
+	rules := parseRobotsTxt(strings.NewReader(robotsTxt), DefaultUserAgent)
+
+	tests := []struct {
+		name     string
+		path     string
+		expected bool
+	}{
+		{
+			name:     "root is allowed for our group",
+			path:     "/",
+			expected: true,
+		},
+		{
+			name:     "disallowed prefix",
+			path:     "/private/notes",
+			expected: false,
+		},
+		{
+			name:     "longer allow wins",
+			path:     "/private/public/page",
+			expected: true,
+		},
+		{
+			name:     "wildcard with end anchor",
+			path:     "/files/report.pdf",
+			expected: false,
+		},
+		{
+			name:     "end anchor does not match longer path",
+			path:     "/files/report.pdf?download=1",
+			expected: true,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := rules.allowed(tc.path)
+			if actual != tc.expected {
+				t.Errorf("Test %v - %s FAIL: expected %v, actual: %v", i, tc.name, tc.expected, actual)
+			}
+		})
+	}
+
+	if rules.delay(defaultCrawlDelay) != 2*time.Second {
+		t.Errorf("expected crawl delay %v, got %v", 2*time.Second, rules.delay(defaultCrawlDelay))
+	}
+	if len(rules.sitemaps) != 1 || rules.sitemaps[0] != "https://blog.test.dev/sitemap.xml" {
+		t.Errorf("expected one sitemap, got %v", rules.sitemaps)
+	}
+}
+
+func TestRobotsWildcardGroup(t *testing.T) {
+	robotsTxt := `
+User-agent: SomeoneElse
+Disallow:
+
+User-agent: *
+Disallow: /admin
+`
+	rules := parseRobotsTxt(strings.NewReader(robotsTxt), DefaultUserAgent)
+
+	if rules.allowed("/admin/login") {
+		t.Errorf("expected /admin/login to be disallowed")
+	}
+	if !rules.allowed("/about") {
+		t.Errorf("expected /about to be allowed")
+	}
+	if rules.delay(defaultCrawlDelay) != defaultCrawlDelay {
+		t.Errorf("expected default crawl delay %v, got %v", defaultCrawlDelay, rules.delay(defaultCrawlDelay))
+	}
+}
+
+func TestRobotsGroupMatching(t *testing.T) {
+	tests := []struct {
+		name      string
+		robotsTxt string
+		path      string
+		expected  bool
+	}{
+		{
+			name:      "product token matches case-insensitively",
+			robotsTxt: "User-agent: *\nDisallow: /\n\nUser-agent: mycrawler\nDisallow: /private\n",
+			path:      "/about",
+			expected:  true,
+		},
+		{
+			name:      "prefix of the product token is another agent",
+			robotsTxt: "User-agent: My\nDisallow:\n\nUser-agent: *\nDisallow: /admin\n",
+			path:      "/admin",
+			expected:  false,
+		},
+		{
+			name:      "agent with the product token as prefix",
+			robotsTxt: "User-agent: MyCrawlerPro\nDisallow: /\n",
+			path:      "/about",
+			expected:  true,
+		},
+		{
+			name:      "first group of a repeated agent",
+			robotsTxt: "User-agent: MyCrawler\nDisallow: /private\n\nUser-agent: *\nDisallow: /\n\nUser-agent: MyCrawler\nDisallow: /drafts\n",
+			path:      "/private/notes",
+			expected:  false,
+		},
+		{
+			name:      "second group of a repeated agent",
+			robotsTxt: "User-agent: MyCrawler\nDisallow: /private\n\nUser-agent: *\nDisallow: /\n\nUser-agent: MyCrawler\nDisallow: /drafts\n",
+			path:      "/drafts/post",
+			expected:  false,
+		},
+		{
+			name:      "repeated wildcard groups",
+			robotsTxt: "User-agent: *\nDisallow: /admin\n\nUser-agent: *\nDisallow: /tmp\n",
+			path:      "/tmp/file",
+			expected:  false,
+		},
+	}
+
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rules := parseRobotsTxt(strings.NewReader(tc.robotsTxt), DefaultUserAgent)
+			actual := rules.allowed(tc.path)
+			if actual != tc.expected {
+				t.Errorf("Test %v - %s FAIL: expected %v, actual: %v", i, tc.name, tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRobotsCacheCancelled(t *testing.T) {
+	fetches := 0
+	cache := newRobotsCache(func(ctx context.Context, rawURL string) (*robotsRules, error) {
+		fetches++
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return parseRobotsTxt(strings.NewReader("User-agent: *\nDisallow: /admin\n"), DefaultUserAgent), nil
+	}, slog.New(slog.DiscardHandler))
+	pageURL, _ := url.Parse("https://blog.web.dev/about")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cache.get(ctx, pageURL); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancelled fetch to fail, got %v", err)
+	}
+
+	// the cancelled fetch is not cached as deny-all
+	rules, err := cache.get(context.Background(), pageURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !rules.allowed("/about") || rules.allowed("/admin") {
+		t.Errorf("expected the fetched rules, got %+v", rules)
+	}
+	if _, err := cache.get(context.Background(), pageURL); err != nil || fetches != 2 {
+		t.Errorf("expected the rules to be cached after 2 fetches, got %d fetches and %v", fetches, err)
+	}
+}
@@ -0,0 +1,87 @@
+package crawl
+
+import (
+	"cmp"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// sameSite reports whether u is on a host of the crawled site: the base
+// URL's host, its subdomains when allowSubdomains is set, any host of its
+// registrable domain when sameDomain is set, or one of the allowed hosts.
+func (c *Crawler) sameSite(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	baseHost := strings.ToLower(c.baseURL.Hostname())
+	switch {
+	case host == baseHost:
+		return true
+	case c.allowSubdomains && strings.HasSuffix(host, "."+baseHost):
+		return true
+	case c.sameDomain && registrableDomain(host) == registrableDomain(baseHost):
+		return true
+	}
+	return hostAllowed(c.allowedHosts, host)
+}
+
+// inScope reports whether u should be crawled: it must be on the same site,
+// under the path prefix if one is set, match one of the include patterns
+// if any are set and match no exclude pattern.
+func (c *Crawler) inScope(u *url.URL) bool {
+	if !c.sameSite(u) {
+		return false
+	}
+	if c.pathPrefix != "" && !strings.HasPrefix(cmp.Or(u.Path, "/"), c.pathPrefix) {
+		return false
+	}
+
+	rawURL := u.String()
+	if len(c.include) > 0 && !matchAny(c.include, rawURL) {
+		return false
+	}
+	return !matchAny(c.exclude, rawURL)
+}
+
+// registrableDomain returns the domain under the public suffix of host,
+// e.g. example.co.uk for www.example.co.uk. IP addresses and hosts without
+// a public suffix, such as localhost, are their own domain.
+func registrableDomain(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
+// hostAllowed matches host against an allowlist of host names, where
+// "*.example.com" stands for any subdomain of example.com.
+func hostAllowed(allowed []string, host string) bool {
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+		if suffix, found := strings.CutPrefix(pattern, "*."); found {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []*regexp.Regexp, s string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
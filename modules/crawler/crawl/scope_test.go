@@ -0,0 +1,147 @@
+package crawl
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestInScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		url      string
+		expected bool
+	}{
+		{"same host", nil, "https://www.example.co.uk/a", true},
+		{"other port", nil, "https://www.example.co.uk:8443/a", true},
+		{"apex", nil, "https://example.co.uk/a", false},
+		{"subdomain", []Option{WithAllowSubdomains(true)}, "https://blog.www.example.co.uk/a", true},
+		{"apex with subdomains", []Option{WithAllowSubdomains(true)}, "https://example.co.uk/a", false},
+		{"apex of the same domain", []Option{WithSameDomain(true)}, "https://example.co.uk/a", true},
+		{"sibling of the same domain", []Option{WithSameDomain(true)}, "https://shop.example.co.uk/a", true},
+		{"other domain under the suffix", []Option{WithSameDomain(true)}, "https://other.co.uk/a", false},
+		{"allowed host", []Option{WithAllowedHosts("cdn.example.net")}, "https://cdn.example.net/a", true},
+		{"allowed wildcard", []Option{WithAllowedHosts("*.example.net")}, "https://docs.example.net/a", true},
+		{"wildcard excludes apex", []Option{WithAllowedHosts("*.example.net")}, "https://example.net/a", false},
+		{"path prefix", []Option{WithPathPrefix("/docs/")}, "https://www.example.co.uk/docs/intro", true},
+		{"outside path prefix", []Option{WithPathPrefix("/docs/")}, "https://www.example.co.uk/blog/", false},
+		{"root outside path prefix", []Option{WithPathPrefix("/docs/")}, "https://www.example.co.uk", false},
+		{"include", []Option{WithInclude(regexp.MustCompile(`/blog/`))}, "https://www.example.co.uk/about", false},
+		{"exclude", []Option{WithExclude(regexp.MustCompile(`\?print`))}, "https://www.example.co.uk/a?print=1", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := New("https://www.example.co.uk/", tc.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			parsedURL, err := url.Parse(tc.url)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := c.inScope(parsedURL); actual != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := map[string]string{
+		"www.example.com":    "example.com",
+		"example.com":        "example.com",
+		"a.b.example.co.uk":  "example.co.uk",
+		"user.github.io":     "user.github.io",
+		"127.0.0.1":          "127.0.0.1",
+		"localhost":          "localhost",
+		"shop.example.local": "example.local",
+	}
+	for host, expected := range tests {
+		if actual := registrableDomain(host); actual != expected {
+			t.Errorf("expected %s for %s, got %s", expected, host, actual)
+		}
+	}
+}
+
+func TestCrawlExternalLinks(t *testing.T) {
+	external := http.NewServeMux()
+	external.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nCrawl-delay: 0.001\n")
+	})
+	external.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	external.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	externalServer := httptest.NewServer(external)
+	defer externalServer.Close()
+	// the same server under another host name
+	externalURL := strings.Replace(externalServer.URL, "127.0.0.1", "localhost", 1)
+
+	site := http.NewServeMux()
+	site.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nCrawl-delay: 0.001\n")
+	})
+	site.HandleFunc("/docs/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><body><a href="/docs/a">a</a><a href="/about">about</a>
+			<a href="%[1]s/ok">ok</a><a href="%[1]s/gone">gone</a><a href="%[1]s/no-head">no head</a>
+			<a href="http://127.0.0.1:1/dead">dead</a></body></html>`, externalURL)
+	})
+	siteServer := httptest.NewServer(site)
+	defer siteServer.Close()
+
+	c := newTestCrawler(t, siteServer.URL+"/docs/", WithPathPrefix("/docs/"))
+	if len(c.Pages()) != 2 {
+		t.Errorf("expected the 2 pages under /docs/, got %v", crawledPaths(c))
+	}
+	links := c.ExternalLinks()
+	if len(links) != 5 {
+		t.Fatalf("expected 5 external links, got %+v", links)
+	}
+	for _, link := range links {
+		if link.Checked || len(link.LinkedFrom) != 2 {
+			t.Errorf("expected an unchecked link from both pages, got %+v", link)
+		}
+	}
+
+	c = newTestCrawler(t, siteServer.URL+"/docs/", WithPathPrefix("/docs/"), WithExternalLinkCheck(2))
+	tests := []struct {
+		url    string
+		status int
+	}{
+		// nothing listens there, robots.txt can't be read either
+		{"http://127.0.0.1:1/dead", 0},
+		{siteServer.URL + "/about", http.StatusNotFound},
+		{externalURL + "/gone", http.StatusNotFound},
+		{externalURL + "/no-head", http.StatusOK},
+		{externalURL + "/ok", http.StatusOK},
+	}
+	links = c.ExternalLinks()
+	if len(links) != len(tests) {
+		t.Fatalf("expected %d external links, got %+v", len(tests), links)
+	}
+	for i, tc := range tests {
+		link := links[i]
+		if link.URL != tc.url || !link.Checked || link.StatusCode != tc.status || link.Broken() != (tc.status == 0 || tc.status >= 400) {
+			t.Errorf("expected %s checked with %d, got %+v", tc.url, tc.status, link)
+		}
+		if tc.status == 0 && link.Error == "" {
+			t.Errorf("expected %s to record the error, got %+v", tc.url, link)
+		}
+	}
+	if skipped := c.Skipped(); len(skipped) != 0 {
+		t.Errorf("expected no skipped links, got %v", skipped)
+	}
+	for _, result := range c.Fetches() {
+		if strings.HasPrefix(result.URL, externalURL) || strings.HasSuffix(result.URL, "/about") {
+			t.Errorf("expected external links not to be crawled, got %+v", result)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package crawl
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	maxSitemapFetches = 50
+	maxSitemapBytes   = 50 * 1024 * 1024 // sitemaps are capped at 50 MiB uncompressed
+)
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// sitemapXML covers both <urlset> and <sitemapindex> documents.
+type sitemapXML struct {
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+// parseSitemap returns the page URLs of a urlset and the child sitemaps of a
+// sitemap index. Gzipped input is detected by its magic bytes.
+func parseSitemap(r io.Reader) (pageURLs []string, sitemapURLs []string, err error) {
+	buffered := bufio.NewReader(r)
+	magic, _ := buffered.Peek(2)
+
+	var body io.Reader = buffered
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	var doc sitemapXML
+	if err := xml.NewDecoder(io.LimitReader(body, maxSitemapBytes)).Decode(&doc); err != nil {
+		return nil, nil, err
+	}
+
+	for _, u := range doc.URLs {
+		if loc := strings.TrimSpace(u.Loc); loc != "" {
+			pageURLs = append(pageURLs, loc)
+		}
+	}
+	for _, s := range doc.Sitemaps {
+		if loc := strings.TrimSpace(s.Loc); loc != "" {
+			sitemapURLs = append(sitemapURLs, loc)
+		}
+	}
+	return pageURLs, sitemapURLs, nil
+}
+
+// discoverSitemaps lists the sitemaps declared in robots.txt plus the
+// conventional /sitemap.xml location.
+func discoverSitemaps(baseURL *url.URL, robots *robotsRules) []string {
+	defaultURL := url.URL{Scheme: baseURL.Scheme, Host: baseURL.Host, Path: "/sitemap.xml"}
+
+	var sitemaps []string
+	if robots != nil {
+		sitemaps = append(sitemaps, robots.sitemaps...)
+	}
+	for _, s := range sitemaps {
+		if s == defaultURL.String() {
+			return sitemaps
+		}
+	}
+	return append(sitemaps, defaultURL.String())
+}
+
+// collectSitemapURLs fetches the given sitemaps, following sitemap indexes,
+// and returns every page URL found.
+func (c *Crawler) collectSitemapURLs(ctx context.Context, sitemaps []string) []string {
+	queue := append([]string(nil), sitemaps...)
+	visited := make(map[string]struct{})
+
+	var pageURLs []string
+	for len(queue) > 0 && len(visited) < maxSitemapFetches {
+		sitemapURL := queue[0]
+		queue = queue[1:]
+		if _, found := visited[sitemapURL]; found {
+			continue
+		}
+		visited[sitemapURL] = struct{}{}
+
+		pages, children, err := c.getSitemap(ctx, sitemapURL)
+		if err != nil {
+			c.logger.Info("sitemap unavailable", "url", sitemapURL, "error", err)
+			continue
+		}
+		pageURLs = append(pageURLs, pages...)
+		queue = append(queue, children...)
+	}
+	return pageURLs
+}
+
+func (c *Crawler) getSitemap(ctx context.Context, rawURL string) ([]string, []string, error) {
+	request, err := c.newRequest(ctx, http.MethodGet, rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 400 {
+		return nil, nil, fmt.Errorf("received status code %d", response.StatusCode)
+	}
+
+	return parseSitemap(response.Body)
+}
+
+// sitemapCoverage compares the sitemap against the crawled link graph. It
+// returns sitemap pages that no crawled page links to, and crawled pages that
+// are linked but absent from the sitemap.
+func sitemapCoverage(pages map[string]PageData, sitemap map[string]string, normalizer *Normalizer) (notLinked, notInSitemap []string) {
+	if len(sitemap) == 0 {
+		// nothing to compare against
+		return nil, nil
+	}
+
+	linked := make(map[string]struct{})
+	for _, pageData := range pages {
+		for _, link := range pageData.OutgoingLinks {
+			normalizedLink, err := normalizer.Normalize(link)
+			if err != nil {
+				continue
+			}
+			linked[normalizedLink] = struct{}{}
+		}
+	}
+
+	for normalized, rawURL := range sitemap {
+		if _, found := linked[normalized]; !found {
+			notLinked = append(notLinked, rawURL)
+		}
+	}
+	for normalized := range linked {
+		pageData, crawled := pages[normalized]
+		if !crawled {
+			continue
+		}
+		if _, found := sitemap[normalized]; !found {
+			notInSitemap = append(notInSitemap, pageData.URL)
+		}
+	}
+
+	sort.Strings(notLinked)
+	sort.Strings(notInSitemap)
+	return notLinked, notInSitemap
+}
@@ -0,0 +1,98 @@
+package crawl
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSitemap(t *testing.T) {
+	urlset := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>https://blog.test.dev/</loc></url>
+	<url><loc> https://blog.test.dev/about </loc></url>
+</urlset>`
+	index := `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap><loc>https://blog.test.dev/sitemap-posts.xml.gz</loc></sitemap>
+</sitemapindex>`
+
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write([]byte(urlset))
+	gz.Close()
+
+	tests := []struct {
+		name             string
+		input            []byte
+		expectedPages    []string
+		expectedSitemaps []string
+	}{
+		{
+			name:          "urlset",
+			input:         []byte(urlset),
+			expectedPages: []string{"https://blog.test.dev/", "https://blog.test.dev/about"},
+		},
+		{
+			name:             "sitemap index",
+			input:            []byte(index),
+			expectedSitemaps: []string{"https://blog.test.dev/sitemap-posts.xml.gz"},
+		},
+		{
+			name:          "gzipped urlset",
+			input:         gzipped.Bytes(),
+			expectedPages: []string{"https://blog.test.dev/", "https://blog.test.dev/about"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pages, sitemaps, err := parseSitemap(bytes.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(pages, tc.expectedPages) {
+				t.Errorf("expected pages %v, got %v", tc.expectedPages, pages)
+			}
+			if !reflect.DeepEqual(sitemaps, tc.expectedSitemaps) {
+				t.Errorf("expected sitemaps %v, got %v", tc.expectedSitemaps, sitemaps)
+			}
+		})
+	}
+}
+
+func TestParseSitemapInvalid(t *testing.T) {
+	_, _, err := parseSitemap(strings.NewReader("not xml"))
+	if err == nil {
+		t.Errorf("expected error for invalid sitemap, got nil")
+	}
+}
+
+func TestSitemapCoverage(t *testing.T) {
+	pages := map[string]PageData{
+		"blog.test.dev": {
+			URL:           "https://blog.test.dev",
+			OutgoingLinks: []string{"https://blog.test.dev/about", "https://other.dev/"},
+		},
+		"blog.test.dev/about": {
+			URL: "https://blog.test.dev/about",
+		},
+	}
+	sitemap := map[string]string{
+		"blog.test.dev":        "https://blog.test.dev/",
+		"blog.test.dev/orphan": "https://blog.test.dev/orphan",
+	}
+
+	notLinked, notInSitemap := sitemapCoverage(pages, sitemap, DefaultNormalizer())
+
+	expectedNotLinked := []string{"https://blog.test.dev/", "https://blog.test.dev/orphan"}
+	if !reflect.DeepEqual(notLinked, expectedNotLinked) {
+		t.Errorf("expected %v, got %v", expectedNotLinked, notLinked)
+	}
+	expectedNotInSitemap := []string{"https://blog.test.dev/about"}
+	if !reflect.DeepEqual(notInSitemap, expectedNotInSitemap) {
+		t.Errorf("expected %v, got %v", expectedNotInSitemap, notInSitemap)
+	}
+}
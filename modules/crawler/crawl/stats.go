@@ -0,0 +1,92 @@
+package crawl
+
+import (
+	"slices"
+	"sort"
+	"time"
+)
+
+// topPages is the number of largest and slowest pages in Stats.
+const topPages = 10
+
+// Stats summarizes a crawl.
+type Stats struct {
+	TotalTime   time.Duration `json:"total_time_ns"`
+	Pages       int           `json:"pages"`
+	Fetched     int           `json:"fetched"`
+	Requests    int64         `json:"requests"`
+	Bytes       int64         `json:"bytes"`
+	StatusCodes map[int]int   `json:"status_codes"` // fetches by final status, 0 if there was no response
+	Latency     Latency       `json:"latency"`
+	Largest     []PageSize    `json:"largest_pages"`
+	Slowest     []PageSize    `json:"slowest_pages"`
+}
+
+// Latency holds percentiles of the response times of the fetches.
+type Latency struct {
+	P50 time.Duration `json:"p50_ns"`
+	P90 time.Duration `json:"p90_ns"`
+	P95 time.Duration `json:"p95_ns"`
+	P99 time.Duration `json:"p99_ns"`
+	Max time.Duration `json:"max_ns"`
+}
+
+// PageSize is a fetched URL with its size and response time.
+type PageSize struct {
+	URL          string        `json:"url"`
+	Size         int64         `json:"size"`
+	ResponseTime time.Duration `json:"response_time_ns"`
+}
+
+// Stats returns the summary of the crawl so far.
+func (c *Crawler) Stats() Stats {
+	progress := c.Progress()
+	fetches := c.Fetches()
+
+	stats := Stats{
+		TotalTime:   progress.Elapsed,
+		Pages:       progress.Pages,
+		Fetched:     progress.Fetched,
+		Requests:    progress.Requests,
+		Bytes:       progress.Bytes,
+		StatusCodes: make(map[int]int),
+	}
+	pages := make([]PageSize, 0, len(fetches))
+	latencies := make([]time.Duration, 0, len(fetches))
+	for _, result := range fetches {
+		stats.StatusCodes[result.StatusCode]++
+		latencies = append(latencies, result.ResponseTime)
+		pages = append(pages, PageSize{URL: result.URL, Size: result.Size, ResponseTime: result.ResponseTime})
+	}
+
+	slices.Sort(latencies)
+	stats.Latency = Latency{
+		P50: percentile(latencies, 50),
+		P90: percentile(latencies, 90),
+		P95: percentile(latencies, 95),
+		P99: percentile(latencies, 99),
+		Max: percentile(latencies, 100),
+	}
+
+	// fetches are sorted by URL, so ties stay in URL order
+	sort.SliceStable(pages, func(i, j int) bool {
+		return pages[i].ResponseTime > pages[j].ResponseTime
+	})
+	stats.Slowest = slices.Clone(pages[:min(topPages, len(pages))])
+	sort.SliceStable(pages, func(i, j int) bool {
+		return pages[i].Size > pages[j].Size
+	})
+	pages = slices.DeleteFunc(pages, func(p PageSize) bool { return p.Size == 0 })
+	stats.Largest = pages[:min(topPages, len(pages))]
+	return stats
+}
+
+// percentile returns the nearest-rank percentile p of sorted values, 0 if
+// there are none.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100 // ceil(p/100 * n)
+	return sorted[max(rank, 1)-1]
+}
@@ -0,0 +1,71 @@
+package crawl
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 0, 20)
+	for i := 1; i <= 20; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+	tests := []struct {
+		values   []time.Duration
+		p        int
+		expected time.Duration
+	}{
+		{sorted, 50, 10 * time.Millisecond},
+		{sorted, 90, 18 * time.Millisecond},
+		{sorted, 95, 19 * time.Millisecond},
+		{sorted, 99, 20 * time.Millisecond},
+		{sorted, 100, 20 * time.Millisecond},
+		{sorted[:1], 50, time.Millisecond},
+		{nil, 50, 0},
+	}
+	for _, tc := range tests {
+		if actual := percentile(tc.values, tc.p); actual != tc.expected {
+			t.Errorf("expected p%d of %d values to be %v, got %v", tc.p, len(tc.values), tc.expected, actual)
+		}
+	}
+}
+
+func TestProgressETA(t *testing.T) {
+	tests := []struct {
+		progress Progress
+		expected time.Duration
+	}{
+		{Progress{Pages: 10, MaxPages: 100, Queued: 5, Elapsed: 5 * time.Second}, 45 * time.Second},
+		{Progress{Pages: 0, MaxPages: 100, Queued: 5, Elapsed: 5 * time.Second}, 0},
+		{Progress{Pages: 100, MaxPages: 100, Queued: 5, Elapsed: 5 * time.Second}, 0},
+		{Progress{Pages: 10, MaxPages: 100, Elapsed: 5 * time.Second}, 0},
+	}
+	for _, tc := range tests {
+		if actual := tc.progress.ETA(); actual != tc.expected {
+			t.Errorf("expected ETA %v for %+v, got %v", tc.expected, tc.progress, actual)
+		}
+	}
+}
+
+func TestCrawlStats(t *testing.T) {
+	server := newTestSite(t)
+	c := newTestCrawler(t, server.URL+"/p/1", WithConcurrency(2), WithMaxDepth(1))
+
+	stats := c.Stats()
+	if stats.Pages != 3 || stats.Fetched != 4 || stats.TotalTime <= 0 {
+		t.Errorf("expected 3 pages, 4 fetches and a total time, got %+v", stats)
+	}
+	if stats.StatusCodes[200] != 3 || stats.StatusCodes[404] != 1 {
+		t.Errorf("expected 3 times 200 and once 404, got %v", stats.StatusCodes)
+	}
+	if stats.Latency.Max <= 0 || stats.Latency.P50 > stats.Latency.Max {
+		t.Errorf("expected latency percentiles, got %+v", stats.Latency)
+	}
+	if len(stats.Slowest) != 4 || stats.Slowest[0].ResponseTime != stats.Latency.Max {
+		t.Errorf("expected the 4 fetches slowest first, got %+v", stats.Slowest)
+	}
+	// the 404 page is not parsed, so it has no size
+	if len(stats.Largest) != 3 || stats.Largest[0].Size < stats.Largest[2].Size {
+		t.Errorf("expected the 3 pages largest first, got %+v", stats.Largest)
+	}
+}
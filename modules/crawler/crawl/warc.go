@@ -0,0 +1,268 @@
+package crawl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"crawler/warc"
+)
+
+// warcArchive writes every request and response of the page fetches to a
+// WARC file, followed by a metadata record with the FetchResult of the
+// page. Records are written whole, so workers can share it.
+type warcArchive struct {
+	mu     sync.Mutex
+	file   *os.File
+	writer *warc.Writer
+	redact []string // request headers archived without their values
+}
+
+// redactedValue replaces the values of credential headers in the archive.
+const redactedValue = "[redacted]"
+
+// openWARC creates the WARC file, or appends to it when resuming, and
+// starts it with a warcinfo record describing the crawl.
+func (c *Crawler) openWARC() (*warcArchive, error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if c.resume {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	file, err := os.OpenFile(c.warcPath, flags, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	// the archive may be shared, the site credentials stay out of it
+	redact := []string{"Authorization", "Cookie", "Proxy-Authorization"}
+	for name := range c.headers {
+		redact = append(redact, name)
+	}
+	archive := &warcArchive{file: file, writer: warc.NewWriter(file), redact: redact}
+	// isPartOf names the crawl, FromWARC reads the base URL from it
+	info := fmt.Sprintf("software: crawler\r\nformat: WARC File Format 1.1\r\n"+
+		"conformsTo: http://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/\r\n"+
+		"robots: obey\r\nhttp-header-user-agent: %s\r\nisPartOf: %s\r\n", c.userAgent, c.baseURL)
+	err = archive.write(&warc.Record{
+		Header: warc.Header{
+			"WARC-Type":     warc.TypeWarcinfo,
+			"WARC-Filename": filepath.Base(c.warcPath),
+			"Content-Type":  "application/warc-fields",
+		},
+		Content: []byte(info),
+	})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return archive, nil
+}
+
+func (a *warcArchive) write(records ...*warc.Record) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, record := range records {
+		if err := a.writer.WriteRecord(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeExchange archives a request and the response to it with its body.
+// response is nil if the request failed; truncated is the WARC-Truncated
+// reason if the body is incomplete.
+func (a *warcArchive) writeExchange(request *http.Request, response *http.Response, body []byte, truncated string) error {
+	if a == nil {
+		return nil
+	}
+
+	archived := *request
+	archived.Header = request.Header.Clone()
+	for _, name := range a.redact {
+		if _, found := archived.Header[http.CanonicalHeaderKey(name)]; found {
+			archived.Header.Set(name, redactedValue)
+		}
+	}
+	var requestBlock bytes.Buffer
+	if err := archived.Write(&requestBlock); err != nil {
+		return err
+	}
+	requestRecord := &warc.Record{
+		Header: warc.Header{
+			"WARC-Type":       warc.TypeRequest,
+			"WARC-Record-ID":  warc.NewRecordID(),
+			"WARC-Target-URI": request.URL.String(),
+			"Content-Type":    "application/http;msgtype=request",
+		},
+		Content: requestBlock.Bytes(),
+	}
+	if response == nil {
+		return a.write(requestRecord)
+	}
+
+	// the body keeps its Content-Encoding, only Transfer-Encoding is
+	// removed by Go's client when it decodes the body
+	var responseBlock bytes.Buffer
+	fmt.Fprintf(&responseBlock, "%s %s\r\n", response.Proto, response.Status)
+	if err := response.Header.Write(&responseBlock); err != nil {
+		return err
+	}
+	responseBlock.WriteString("\r\n")
+	responseBlock.Write(body)
+
+	responseRecord := &warc.Record{
+		Header: warc.Header{
+			"WARC-Type":       warc.TypeResponse,
+			"WARC-Record-ID":  warc.NewRecordID(),
+			"WARC-Target-URI": request.URL.String(),
+			"Content-Type":    "application/http;msgtype=response",
+		},
+		Content: responseBlock.Bytes(),
+	}
+	if truncated != "" {
+		responseRecord.Header["WARC-Truncated"] = truncated
+	}
+	requestRecord.Header["WARC-Concurrent-To"] = responseRecord.Header["WARC-Record-ID"]
+	return a.write(requestRecord, responseRecord)
+}
+
+// writeMetadata archives the outcome of fetching a page.
+func (a *warcArchive) writeMetadata(result FetchResult) error {
+	if a == nil {
+		return nil
+	}
+	content, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return a.write(&warc.Record{
+		Header: warc.Header{
+			"WARC-Type":       warc.TypeMetadata,
+			"WARC-Target-URI": result.URL,
+			"Content-Type":    "application/json",
+		},
+		Content: content,
+	})
+}
+
+func (a *warcArchive) close() error {
+	if a == nil {
+		return nil
+	}
+	err := a.file.Sync()
+	if closeErr := a.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("closing WARC file: %w", err)
+	}
+	return nil
+}
+
+// FromWARC rebuilds a crawl from a WARC archive written with WithWARC,
+// without any network access. The fetches come from the metadata records
+// and the pages are extracted again from the archived responses, so
+// options such as WithExtractors and WithNormalizer apply. Archives of
+// several crawls may be concatenated; a page answered with 304 Not
+// Modified is rebuilt from the last successful response for its URL.
+//
+// The returned Crawler holds the pages and fetches of the archive and must
+// not be Run. Robots.txt, sitemaps and assets are not archived, so their
+// results stay empty.
+func FromWARC(r io.Reader, opts ...Option) (*Crawler, error) {
+	reader, err := warc.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var c *Crawler
+	bodies := make(map[string][]byte) // last successful response body by URL
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		targetURI := record.Header.Get("WARC-Target-URI")
+		switch record.Type() {
+		case warc.TypeWarcinfo:
+			if c != nil {
+				continue
+			}
+			c, err = New(warc.Fields(record.Content).Get("isPartOf"), opts...)
+			if err != nil {
+				return nil, fmt.Errorf("warcinfo record: %w", err)
+			}
+		case warc.TypeResponse:
+			response, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(record.Content)), nil)
+			if err != nil {
+				return nil, fmt.Errorf("response record for %s: %w", targetURI, err)
+			}
+			if response.StatusCode < 200 || response.StatusCode >= 300 ||
+				!strings.Contains(response.Header.Get("Content-Type"), "text/html") {
+				continue
+			}
+			var maxBodySize int64
+			if c != nil {
+				maxBodySize = c.maxBodySize
+			}
+			body, _, err := readPageBody(response.Header, response.Body, maxBodySize)
+			if err != nil && !(record.Header.Get("WARC-Truncated") != "" && errors.Is(err, io.ErrUnexpectedEOF)) {
+				return nil, fmt.Errorf("response record for %s: %w", targetURI, err)
+			}
+			bodies[targetURI] = []byte(body)
+		case warc.TypeMetadata:
+			if c == nil {
+				return nil, errors.New("metadata record before the warcinfo record")
+			}
+			var result FetchResult
+			if err := json.Unmarshal(record.Content, &result); err != nil {
+				return nil, fmt.Errorf("metadata record for %s: %w", targetURI, err)
+			}
+			c.replayFetch(result, bodies[result.FinalURL])
+		}
+	}
+	if c == nil {
+		return nil, errors.New("no warcinfo record in the archive")
+	}
+	return c, nil
+}
+
+// replayFetch stores an archived fetch the way crawlPage stores a live
+// one. A later fetch of the same URL replaces an earlier one.
+func (c *Crawler) replayFetch(result FetchResult, body []byte) {
+	normalizedURL, err := c.normalizer.Normalize(result.URL)
+	if err != nil {
+		return
+	}
+	c.setFetchResult(normalizedURL, result)
+	if result.Error != "" || !result.IsHTML() {
+		return
+	}
+	if len(result.Redirects) > 0 {
+		parsedFinalURL, err := url.Parse(result.FinalURL)
+		if err != nil || !c.sameSite(parsedFinalURL) {
+			return
+		}
+	}
+	if body == nil {
+		c.logger.Warn("no archived response", "url", result.FinalURL)
+		return
+	}
+
+	pageData := c.extractPage(result.URL, result, string(body))
+	c.mergePage(c.pageKey(pageData), pageData)
+}
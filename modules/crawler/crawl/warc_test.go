@@ -0,0 +1,165 @@
+package crawl
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"crawler/warc"
+)
+
+func readWARCFiles(t *testing.T, paths ...string) *Crawler {
+	t.Helper()
+	readers := make([]io.Reader, 0, len(paths))
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		t.Cleanup(func() { file.Close() })
+		readers = append(readers, file)
+	}
+	c, err := FromWARC(io.MultiReader(readers...))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return c
+}
+
+func TestCrawlWARC(t *testing.T) {
+	server := newTestSite(t)
+	path := filepath.Join(t.TempDir(), "crawl.warc.gz")
+	c := newTestCrawler(t, server.URL+"/p/1", WithMaxPages(10), WithWARC(path))
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer file.Close()
+	r, err := warc.NewReader(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	counts := make(map[string]int)
+	for {
+		record, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		counts[record.Type()]++
+	}
+	fetches := len(c.Fetches())
+	expected := map[string]int{warc.TypeWarcinfo: 1, warc.TypeRequest: fetches, warc.TypeResponse: fetches, warc.TypeMetadata: fetches}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("expected %v, got %v", expected, counts)
+	}
+
+	rebuilt := readWARCFiles(t, path)
+	if rebuilt.BaseURL().String() != server.URL+"/p/1" {
+		t.Errorf("expected base URL %s, got %s", server.URL+"/p/1", rebuilt.BaseURL())
+	}
+	if !reflect.DeepEqual(rebuilt.Pages(), c.Pages()) {
+		t.Errorf("expected the archived pages %v, got %v", crawledPaths(c), crawledPaths(rebuilt))
+	}
+	if !reflect.DeepEqual(rebuilt.Fetches(), c.Fetches()) {
+		t.Errorf("expected the archived fetches %+v, got %+v", c.Fetches(), rebuilt.Fetches())
+	}
+	if len(rebuilt.BrokenLinks()) != 1 {
+		t.Errorf("expected the broken /missing link, got %+v", rebuilt.BrokenLinks())
+	}
+}
+
+func TestCrawlWARCRedactsCredentials(t *testing.T) {
+	server := newTestSite(t)
+	path := filepath.Join(t.TempDir(), "crawl.warc.gz")
+	newTestCrawler(t, server.URL+"/p/1", WithMaxPages(3), WithWARC(path),
+		WithBasicAuth("stage", "hunter2"),
+		WithHeader("X-Token", "token-secret"),
+		WithCookies(&http.Cookie{Name: "session", Value: "cookie-secret"}),
+	)
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer file.Close()
+	r, err := warc.NewReader(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	requests := 0
+	for {
+		record, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		content := string(record.Content)
+		// "c3RhZ2U6aHVudGVyMg==" is the basic auth of stage:hunter2
+		for _, secret := range []string{"hunter2", "c3RhZ2U6aHVudGVyMg==", "token-secret", "cookie-secret"} {
+			if strings.Contains(content, secret) {
+				t.Errorf("expected %q to be redacted from the %s record, got\n%s", secret, record.Type(), content)
+			}
+		}
+		if record.Type() == warc.TypeRequest {
+			requests++
+			if !strings.Contains(content, "X-Token: "+redactedValue) || !strings.Contains(content, "Authorization: "+redactedValue) {
+				t.Errorf("expected the redacted headers in the request record, got\n%s", content)
+			}
+		}
+	}
+	if requests == 0 {
+		t.Errorf("expected request records")
+	}
+}
+
+func TestCrawlWARCNotModified(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nCrawl-delay: 0.001\n")
+	})
+	mux.HandleFunc("/{$}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"home"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"home"`)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><h1>Home</h1></body></html>`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.warc.gz"), filepath.Join(dir, "second.warc.gz")
+
+	newTestCrawler(t, server.URL+"/", WithCache(filepath.Join(dir, "cache")), WithWARC(first))
+	c := newTestCrawler(t, server.URL+"/", WithCache(filepath.Join(dir, "cache")), WithWARC(second))
+	if len(c.Pages()) != 1 || c.Fetches()[0].Change != ChangeUnchanged {
+		t.Fatalf("expected the home page to be revalidated, got %+v", c.Fetches())
+	}
+
+	// the 304 alone has no page to rebuild
+	if pages := readWARCFiles(t, second).Pages(); len(pages) != 0 {
+		t.Errorf("expected no pages without the earlier archive, got %v", pages)
+	}
+	if pages := readWARCFiles(t, first, second).Pages(); !reflect.DeepEqual(pages, c.Pages()) {
+		t.Errorf("expected %v, got %v", c.Pages(), pages)
+	}
+}
+
+func TestFromWARCWithoutWarcinfo(t *testing.T) {
+	if _, err := FromWARC(strings.NewReader("")); err == nil {
+		t.Errorf("expected error for an archive without warcinfo record, got nil")
+	}
+}
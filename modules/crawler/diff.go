@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"crawler/report"
+)
+
+// runDiff implements "crawler diff [flags] <OLD_REPORT> <NEW_REPORT>".
+func runDiff(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("crawler diff", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: crawler diff [flags] <OLD_REPORT> <NEW_REPORT>\n\n")
+		fmt.Fprintf(fs.Output(), "Reports can be in any format; the format is taken from the file extension.\n\n")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "text", "output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("diff needs two reports")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown diff format %q", *format)
+	}
+
+	oldReport, err := readReportFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	newReport, err := readReportFile(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	d := report.Compare(oldReport, newReport)
+	if *format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(d)
+	}
+	return d.WriteText(stdout)
+}
+
+// readReportFile reads a report written by the crawler. For CSV the fetch
+// statuses come from the status.csv next to it, if there is one.
+func readReportFile(filename string) (*report.Report, error) {
+	format, err := report.FormatFromPath(filename)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rep, err := report.Read(file, format)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", filename, err)
+	}
+	if format != "csv" {
+		return rep, nil
+	}
+
+	statusFile, err := os.Open(filepath.Join(filepath.Dir(filename), "status.csv"))
+	if errors.Is(err, os.ErrNotExist) {
+		return rep, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer statusFile.Close()
+	if err := report.ReadStatusCSV(statusFile, rep); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", statusFile.Name(), err)
+	}
+	return rep, nil
+}
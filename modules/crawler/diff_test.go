@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunDiff(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "old.jsonl")
+	newPath := filepath.Join(dir, "new", "report.csv")
+	files := map[string]string{
+		oldPath: `{"type":"page","url":"https://blog.test.dev/a","h1":"A","title":"A"}
+{"type":"page","url":"https://blog.test.dev/b","h1":"B"}
+{"type":"fetch","url":"https://blog.test.dev/c","status_code":200}
+`,
+		newPath: "page_url,h1,title\n" +
+			"https://blog.test.dev/a,A,A2\n",
+		filepath.Join(dir, "new", "status.csv"): "url,final_url,status_code,content_type,response_time_ms,error\n" +
+			"https://blog.test.dev/c,https://blog.test.dev/c,404,text/html,3,received status code 404\n",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := runDiff([]string{oldPath, newPath}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{
+		"- https://blog.test.dev/b\n",
+		"~ https://blog.test.dev/a title: \"A\" -> \"A2\"\n",
+		"~ https://blog.test.dev/c status: 200 -> 404 (received status code 404)\n",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in output, got %q", expected, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := runDiff([]string{"--format", "json", oldPath, newPath}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), `"removed": [`) {
+		t.Errorf("expected JSON output, got %q", out.String())
+	}
+
+	if err := runDiff([]string{oldPath}, &out); err == nil {
+		t.Errorf("expected error for a single report, got nil")
+	}
+}
@@ -1,106 +1,310 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
-	"net/url"
 	"os"
-	"strconv"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
+
+	"crawler/crawl"
+	"crawler/report"
 )
 
+// subcommands run instead of a crawl when named by the first argument.
+var subcommands = map[string]func(args []string, stdout io.Writer) error{
+	"diff":      runDiff,
+	"read-warc": runReadWARC,
+	"serve":     runServe,
+}
+
 func main() {
-	actualArgs := os.Args[1:]
-	if len(actualArgs) < 3 {
-		fmt.Println("no website provided")
+	if len(os.Args) > 1 {
+		if run, found := subcommands[os.Args[1]]; found {
+			err := run(os.Args[2:], os.Stdout)
+			if err != nil && !errors.Is(err, flag.ErrHelp) {
+				fmt.Println(err)
+				os.Exit(1)
+				return
+			}
+			os.Exit(0)
+			return
+		}
+	}
+
+	cfg, err := parseArgs(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+		return
+	}
+	if err != nil {
+		fmt.Println(err)
 		os.Exit(1)
 		return
 	}
 
-	if len(actualArgs) > 3 {
-		fmt.Println("too many arguments provided")
+	opts, err := cfg.crawlOptions()
+	if err != nil {
+		fmt.Println(err)
 		os.Exit(1)
 		return
 	}
 
-	baseURL := actualArgs[0]
-	maxConcurrencyStr := actualArgs[1]
-	maxPagesStr := actualArgs[2]
+	// --quiet leaves only the errors
+	out := io.Writer(os.Stdout)
+	if cfg.Quiet {
+		out = io.Discard
+	}
+	logOutput := io.Writer(os.Stderr)
+	if !cfg.Quiet && isTerminal(os.Stderr) {
+		logOutput = lineClearingWriter{os.Stderr}
+	}
+	opts = append(opts, crawl.WithLogger(cfg.logger(logOutput)))
 
-	maxConcurrent, err := strconv.Atoi(maxConcurrencyStr)
-	if err != nil || maxConcurrent <= 0 {
-		fmt.Println("invalid max concurrency value")
+	stopMetrics := func() {}
+	if cfg.MetricsAddr != "" {
+		metrics := crawl.NewMetrics()
+		opts = append(opts, crawl.WithMetrics(metrics))
+		stopMetrics, err = serveMetrics(cfg.MetricsAddr, metrics)
+		if err != nil {
+			fmt.Printf("error serving metrics: %v\n", err)
+			os.Exit(1)
+			return
+		}
+		fmt.Fprintf(out, "serving metrics on http://%s/metrics\n", cfg.MetricsAddr)
+	}
+	fmt.Fprintf(out, "starting crawl\n%s\n\n", cfg.URL)
+
+	crawler, err := crawl.New(cfg.URL, opts...)
+	if err != nil {
+		fmt.Printf("error setting up the crawl: %v\n", err)
 		os.Exit(1)
 		return
 	}
-	if maxConcurrent > 5 {
-		fmt.Println("max concurrency value is 5")
-		maxConcurrent = 5
+
+	// on Ctrl-C stop crawling but still write what we have; a second
+	// Ctrl-C kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+	stopProgress := func() {}
+	if !cfg.Quiet {
+		stopProgress = showProgress(os.Stderr, crawler)
+	}
+	err = crawler.Run(ctx)
+	stopProgress()
+	stopMetrics()
+	if err != nil {
+		fmt.Printf("crawl stopped: %v\n", err)
+	}
+	interrupted := ctx.Err() != nil
+	stop()
+	if interrupted {
+		fmt.Fprintf(out, "crawl interrupted, writing a partial report\n")
+		if cfg.Checkpoint != "" {
+			fmt.Fprintf(out, "continue with --resume --checkpoint %s\n", cfg.Checkpoint)
+		}
+	} else {
+		fmt.Fprintf(out, "crawl finished\n")
 	}
 
-	maxPages, err := strconv.Atoi(maxPagesStr)
-	if err != nil || maxPages <= 0 {
-		fmt.Println("invalid max pages value")
-		os.Exit(1)
-		return
+	stats := crawler.Stats()
+	fmt.Fprintf(out, "crawled %d pages in %s: %d requests, %s, latency p50 %s, p95 %s\n",
+		stats.Pages, stats.TotalTime.Round(time.Millisecond), stats.Requests, formatBytes(stats.Bytes),
+		stats.Latency.P50.Round(time.Millisecond), stats.Latency.P95.Round(time.Millisecond))
+
+	rep := report.Build(crawler)
+
+	fmt.Fprintf(out, "\nbroken links: %d\n", len(rep.BrokenLinks))
+	for _, brokenLink := range rep.BrokenLinks {
+		fmt.Fprintf(out, "Broken link: %s (%d %s) linked from %d pages\n", brokenLink.URL, brokenLink.StatusCode, brokenLink.Error, len(brokenLink.LinkedFrom))
 	}
 
-	// fmt.Printf("Max Concurrency: %d\n", maxConcurrent)
-	// fmt.Printf("Max Pages: %d\n", maxPages)
-	fmt.Printf("starting crawl\n%s\n\n", baseURL)
+	fmt.Fprintf(out, "\nredirected urls: %d\n", len(rep.Redirects))
+	for _, result := range rep.Redirects {
+		switch {
+		case result.RedirectLoop:
+			fmt.Fprintf(out, "Redirect loop: %s\n", result.URL)
+		case len(result.Redirects) > 1:
+			fmt.Fprintf(out, "Redirect chain: %s -> %s (%d hops)\n", result.URL, result.FinalURL, len(result.Redirects))
+		}
+	}
 
-	parsedBaseURL, err := url.Parse(baseURL)
-	if err != nil {
-		fmt.Printf("error parsing base URL: %v\n", err)
-		os.Exit(1)
-		return
+	if cfg.CheckAssets {
+		fmt.Fprintf(out, "\nchecked assets: %d\n", len(rep.Assets))
+		for _, asset := range rep.Assets {
+			if len(asset.Issues) > 0 {
+				fmt.Fprintf(out, "Asset issue: %s (%d, %d bytes) %s\n", asset.URL, asset.StatusCode, asset.Size, strings.Join(asset.Issues, ", "))
+			}
+		}
 	}
 
-	cfg := newConfig(parsedBaseURL, maxConcurrent, maxPages)
+	fmt.Fprintf(out, "\nexternal links: %d\n", len(rep.External))
+	for _, link := range rep.External {
+		if link.Broken() {
+			fmt.Fprintf(out, "Broken external link: %s (%d %s) linked from %d pages\n", link.URL, link.StatusCode, link.Error, len(link.LinkedFrom))
+		}
+	}
 
-	cfg.wg.Add(1)
-	cfg.crawlPage(baseURL)
+	if cfg.CacheDir != "" {
+		fmt.Fprintf(out, "\nnew or changed since the last crawl: %d\n", len(rep.Changes))
+		for _, result := range rep.Changes {
+			fmt.Fprintf(out, "Page %s: %s\n", result.Change, result.URL)
+		}
+	}
 
-	cfg.wg.Wait()
-	fmt.Printf("crawl finished\n")
-	for _, pageData := range cfg.pages {
-		fmt.Printf("Found page: %s\n", pageData.URL)
+	fmt.Fprintf(out, "\nduplicate content groups: %d\n", len(rep.Duplicates))
+	for _, group := range rep.Duplicates {
+		fmt.Fprintf(out, "Duplicate content (%s, %.2f): %s\n", group.Kind, group.Similarity, strings.Join(group.URLs, " "))
 	}
 
-	writeCSVReport(cfg.pages, "report.csv")
-	fmt.Printf("\nreport generated: report.csv\n")
+	var orphans []string
+	for _, pageURL := range rep.Graph.Pages {
+		if rep.Graph.Metrics[pageURL].Orphan {
+			orphans = append(orphans, pageURL)
+		}
+	}
+	fmt.Fprintf(out, "\norphan pages: %d\n", len(orphans))
+	for _, pageURL := range orphans {
+		fmt.Fprintf(out, "Orphan page: %s\n", pageURL)
+	}
+
+	fmt.Fprintf(out, "\nskipped by robots.txt: %d\n", len(rep.Skipped))
+	for _, skippedURL := range rep.Skipped {
+		fmt.Fprintf(out, "Skipped page: %s\n", skippedURL)
+	}
+
+	fmt.Fprintf(out, "\nin sitemap but never linked: %d\n", len(rep.Sitemap.NotLinked))
+	for _, pageURL := range rep.Sitemap.NotLinked {
+		fmt.Fprintf(out, "Not linked: %s\n", pageURL)
+	}
+	fmt.Fprintf(out, "\nlinked but missing from sitemap: %d\n", len(rep.Sitemap.NotInSitemap))
+	for _, pageURL := range rep.Sitemap.NotInSitemap {
+		fmt.Fprintf(out, "Not in sitemap: %s\n", pageURL)
+	}
+
+	if err := writeReports(out, cfg.Output, cfg.Format, rep); err != nil {
+		fmt.Printf("error writing report: %v\n", err)
+		os.Exit(1)
+		return
+	}
+	if err := writeGraphs(out, cfg.Graph, rep); err != nil {
+		fmt.Printf("error writing link graph: %v\n", err)
+		os.Exit(1)
+		return
+	}
+	if cfg.StatsJSON != "" {
+		if err := writeStatsJSON(cfg.StatsJSON, stats); err != nil {
+			fmt.Printf("error writing stats: %v\n", err)
+			os.Exit(1)
+			return
+		}
+		fmt.Fprintf(out, "stats generated: %s\n", cfg.StatsJSON)
+	}
+	if interrupted {
+		os.Exit(130)
+		return
+	}
 	os.Exit(0)
 }
 
-func getHTML(rawURL string) (string, error) {
-	request, err := http.NewRequest("GET", rawURL, nil)
+// writeReports writes rep to output in format. The CSV page report has no
+// room for the other results, they get their own files next to it.
+func writeReports(stdout io.Writer, output, format string, rep *report.Report) error {
+	writer, err := report.NewWriter(format)
 	if err != nil {
-		return "", err
+		return err
 	}
+	if err := writeReportFile(output, rep, writer.Write); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "\nreport generated: %s\n", output)
+
+	if format != "csv" {
+		return nil
+	}
+	outputDir := filepath.Dir(output)
+	for _, side := range sideReports(rep) {
+		if side.empty {
+			continue
+		}
+		sideFile := filepath.Join(outputDir, side.name)
+		if err := writeReportFile(sideFile, rep, side.write); err != nil {
+			fmt.Printf("error writing report: %v\n", err)
+			continue
+		}
+		fmt.Fprintf(stdout, "report generated: %s\n", sideFile)
+	}
+	return nil
+}
 
-	request.Header.Add("User-Agent", "MyCrawler/1.0") // Set a custom User-Agent
-	response, err := http.DefaultClient.Do(request)
+// serveMetrics serves the metrics at /metrics on addr until stop is
+// called.
+func serveMetrics(addr string, metrics *crawl.Metrics) (stop func(), err error) {
+	listener, err := net.Listen("tcp", addr)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer response.Body.Close()
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics)
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	return func() { server.Close() }, nil
+}
+
+// sideReport is a CSV file written next to the CSV page report.
+type sideReport struct {
+	name  string
+	empty bool
+	write func(io.Writer, *report.Report) error
+}
 
-	if response.StatusCode > 400 {
-		return "", fmt.Errorf("received status code %d", response.StatusCode)
+func sideReports(rep *report.Report) []sideReport {
+	return []sideReport{
+		{"status.csv", len(rep.Fetches) == 0, report.WriteStatusCSV},
+		{"broken_links.csv", len(rep.BrokenLinks) == 0, report.WriteBrokenLinksCSV},
+		{"redirects.csv", len(rep.Redirects) == 0, report.WriteRedirectsCSV},
+		{"links.csv", len(rep.Pages) == 0, report.WriteLinksCSV},
+		{"assets.csv", len(rep.Assets) == 0, report.WriteAssetsCSV},
+		{"external_links.csv", len(rep.External) == 0, report.WriteExternalLinksCSV},
+		{"changes.csv", len(rep.Changes) == 0, report.WriteChangesCSV},
+		{"duplicates.csv", len(rep.Duplicates) == 0, report.WriteDuplicatesCSV},
+		{"skipped.csv", len(rep.Skipped) == 0, report.WriteSkippedCSV},
+		{"sitemap.csv", len(rep.Sitemap.NotLinked) == 0 && len(rep.Sitemap.NotInSitemap) == 0, report.WriteSitemapCSV},
 	}
+}
 
-	if !strings.Contains(
-		response.Header.Get("Content-Type"),
-		"text/html",
-	) {
-		return "", fmt.Errorf("invalid content type: %s", response.Header.Get("Content-Type"))
+// writeGraphs exports the link graph to every path, in the format of its
+// extension.
+func writeGraphs(stdout io.Writer, paths []string, rep *report.Report) error {
+	for _, path := range paths {
+		write, err := report.GraphWriterFor(path)
+		if err != nil {
+			return err
+		}
+		if err := writeReportFile(path, rep, write); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "link graph generated: %s\n", path)
 	}
+	return nil
+}
 
-	result, err := io.ReadAll(response.Body)
+func writeReportFile(filename string, rep *report.Report, write func(io.Writer, *report.Report) error) error {
+	file, err := os.Create(filename)
 	if err != nil {
-		return "", err
+		return err
 	}
-
-	return string(result), nil
+	if err := write(file, rep); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
 }
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"crawler/crawl"
+)
+
+// showProgress prints the progress of a running crawl to w until the
+// returned stop is called. A terminal gets one line rewritten every
+// second, anything else a new line every ten seconds.
+func showProgress(w *os.File, crawler *crawl.Crawler) (stop func()) {
+	terminal := isTerminal(w)
+	interval := 10 * time.Second
+	if terminal {
+		interval = time.Second
+	}
+	printLine := func() {
+		if terminal {
+			fmt.Fprintf(w, "\r\033[K%s", progressLine(crawler.Progress()))
+			return
+		}
+		fmt.Fprintln(w, progressLine(crawler.Progress()))
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				printLine()
+			case <-done:
+				printLine()
+				if terminal {
+					fmt.Fprintln(w)
+				}
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// progressLine formats the counters of a crawl for showProgress.
+func progressLine(p crawl.Progress) string {
+	line := fmt.Sprintf("pages %d/%d, %d queued, %d failed | %.1f req/s | %s",
+		p.Pages, p.MaxPages, p.Queued, p.Failed, p.RequestsPerSecond(), formatBytes(p.Bytes))
+	if eta := p.ETA().Round(time.Second); eta > 0 {
+		line += " | ETA " + eta.String()
+	}
+	return line
+}
+
+// formatBytes formats a byte count with a binary unit, e.g. "1.5 MiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, exponent := float64(n)/unit, 0
+	for value >= unit && exponent < 3 {
+		value /= unit
+		exponent++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGT"[exponent])
+}
+
+// lineClearingWriter clears the progress line on a terminal before each
+// write, so log lines don't run into it. The next tick redraws it.
+type lineClearingWriter struct {
+	w io.Writer
+}
+
+func (l lineClearingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(l.w, "\r\033[K"); err != nil {
+		return 0, err
+	}
+	return l.w.Write(p)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// writeStatsJSON writes the crawl statistics to filename.
+func writeStatsJSON(filename string, stats crawl.Stats) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(stats); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"crawler/crawl"
+)
+
+func TestProgressLine(t *testing.T) {
+	tests := []struct {
+		progress crawl.Progress
+		expected string
+	}{
+		{
+			crawl.Progress{Pages: 25, MaxPages: 100, Queued: 40, Failed: 2, Requests: 60, Bytes: 3 << 20, Elapsed: 10 * time.Second},
+			"pages 25/100, 40 queued, 2 failed | 6.0 req/s | 3.0 MiB | ETA 30s",
+		},
+		{
+			crawl.Progress{MaxPages: 100, Queued: 1},
+			"pages 0/100, 1 queued, 0 failed | 0.0 req/s | 0 B",
+		},
+	}
+	for _, tc := range tests {
+		if actual := progressLine(tc.progress); actual != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, actual)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n        int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KiB"},
+		{10 << 20, "10.0 MiB"},
+		{3 << 30, "3.0 GiB"},
+	}
+	for _, tc := range tests {
+		if actual := formatBytes(tc.n); actual != tc.expected {
+			t.Errorf("expected %q for %d, got %q", tc.expected, tc.n, actual)
+		}
+	}
+}
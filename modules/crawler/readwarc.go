@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"crawler/crawl"
+	"crawler/report"
+)
+
+// runReadWARC implements "crawler read-warc [flags] <WARC_FILE>...": it
+// rebuilds the report of an archived crawl without network access.
+func runReadWARC(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("crawler read-warc", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: crawler read-warc [flags] <WARC_FILE>...\n\n")
+		fmt.Fprintf(fs.Output(), "Files of several crawls are read in order, later fetches replace earlier ones.\n\n")
+		fs.PrintDefaults()
+	}
+	output := fs.String("output", "report.csv", "report file")
+	format := fs.String("format", "csv", "report format: "+strings.Join(report.Formats(), ", "))
+	var graphs []string
+	fs.Var(&stringList{values: &graphs}, "graph", "export the link graph to this .dot, .graphml or .json file (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no WARC file provided")
+	}
+	if _, err := report.NewWriter(*format); err != nil {
+		return err
+	}
+	for _, path := range graphs {
+		if _, err := report.GraphWriterFor(path); err != nil {
+			return err
+		}
+	}
+
+	readers := make([]io.Reader, 0, fs.NArg())
+	for _, filename := range fs.Args() {
+		file, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		readers = append(readers, file)
+	}
+	crawler, err := crawl.FromWARC(io.MultiReader(readers...))
+	if err != nil {
+		return fmt.Errorf("reading archive: %w", err)
+	}
+
+	rep := report.Build(crawler)
+	fmt.Fprintf(stdout, "rebuilt %d pages from %d fetches of %s\n", len(rep.Pages), len(rep.Fetches), rep.BaseURL)
+	if err := writeReports(stdout, *output, *format, rep); err != nil {
+		return err
+	}
+	return writeGraphs(stdout, graphs, rep)
+}
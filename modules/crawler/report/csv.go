@@ -0,0 +1,419 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"crawler/crawl"
+)
+
+type csvWriter struct{}
+
+type pageColumn struct {
+	name  string
+	value func(crawl.PageData) string
+}
+
+// pageColumns are the columns of the CSV page report. List values are
+// joined with ";", key/value pairs are written as key=value.
+var pageColumns = []pageColumn{
+	{"page_url", func(p crawl.PageData) string { return p.URL }},
+	{"h1", func(p crawl.PageData) string { return p.H1 }},
+	{"first_paragraph", func(p crawl.PageData) string { return p.FirstParagraph }},
+	{"outgoing_link_urls", func(p crawl.PageData) string { return joinStrings(p.OutgoingLinks) }},
+	{"image_urls", func(p crawl.PageData) string { return joinStrings(p.ImageURLs) }},
+	{"canonical_url", func(p crawl.PageData) string { return p.Canonical }},
+	{"alternate_urls", func(p crawl.PageData) string { return joinStrings(p.Alternates) }},
+	{"title", func(p crawl.PageData) string { return p.Title }},
+	{"meta_description", func(p crawl.PageData) string { return p.MetaDescription }},
+	{"meta_robots", func(p crawl.PageData) string { return p.MetaRobots }},
+	{"word_count", func(p crawl.PageData) string { return strconv.Itoa(p.WordCount) }},
+	{"headings", func(p crawl.PageData) string {
+		headings := make([]string, 0, len(p.Headings))
+		for _, heading := range p.Headings {
+			headings = append(headings, fmt.Sprintf("h%d=%s", heading.Level, heading.Text))
+		}
+		return joinStrings(headings)
+	}},
+	{"hreflang", func(p crawl.PageData) string {
+		alternates := make([]string, 0, len(p.Hreflang))
+		for _, alternate := range p.Hreflang {
+			alternates = append(alternates, alternate.Lang+"="+alternate.URL)
+		}
+		return joinStrings(alternates)
+	}},
+	{"open_graph", func(p crawl.PageData) string { return joinPairs(p.OpenGraph) }},
+	{"twitter_card", func(p crawl.PageData) string { return joinPairs(p.TwitterCard) }},
+	{"json_ld", func(p crawl.PageData) string {
+		if len(p.JSONLD) == 0 {
+			return ""
+		}
+		// a JSON array, the blocks themselves contain ";"
+		encoded, _ := json.Marshal(p.JSONLD)
+		return string(encoded)
+	}},
+}
+
+func joinStrings(items []string) string {
+	return strings.Join(items, ";")
+}
+
+func joinPairs(pairs map[string]string) string {
+	keys := slices.Sorted(maps.Keys(pairs))
+	joined := make([]string, 0, len(keys))
+	for _, key := range keys {
+		joined = append(joined, key+"="+pairs[key])
+	}
+	return joinStrings(joined)
+}
+
+// extraColumns adds an extra_<name> column for every custom extractor
+// that produced a value on any page.
+func extraColumns(pages []crawl.PageData) []pageColumn {
+	names := make(map[string]struct{})
+	for _, pageData := range pages {
+		for name := range pageData.Extra {
+			names[name] = struct{}{}
+		}
+	}
+
+	columns := make([]pageColumn, 0, len(names))
+	for _, name := range slices.Sorted(maps.Keys(names)) {
+		columns = append(columns, pageColumn{"extra_" + name, func(p crawl.PageData) string {
+			return joinStrings(p.Extra[name])
+		}})
+	}
+	return columns
+}
+
+// metricColumns adds the link graph metrics if the pages have them.
+func metricColumns(pages []crawl.PageData) []pageColumn {
+	if !slices.ContainsFunc(pages, func(p crawl.PageData) bool { return p.Metrics != nil }) {
+		return nil
+	}
+	metric := func(name string, value func(crawl.PageMetrics) string) pageColumn {
+		return pageColumn{name, func(p crawl.PageData) string {
+			if p.Metrics == nil {
+				return ""
+			}
+			return value(*p.Metrics)
+		}}
+	}
+	return []pageColumn{
+		metric("in_degree", func(m crawl.PageMetrics) string { return strconv.Itoa(m.InDegree) }),
+		metric("out_degree", func(m crawl.PageMetrics) string { return strconv.Itoa(m.OutDegree) }),
+		metric("click_depth", func(m crawl.PageMetrics) string { return strconv.Itoa(m.Depth) }),
+		metric("pagerank", func(m crawl.PageMetrics) string { return strconv.FormatFloat(m.PageRank, 'f', 6, 64) }),
+		metric("orphan", func(m crawl.PageMetrics) string { return strconv.FormatBool(m.Orphan) }),
+	}
+}
+
+// Write writes one row per page. Fetch statuses, broken links, skipped URLs
+// and sitemap coverage have their own files, see the Write*CSV functions.
+func (csvWriter) Write(w io.Writer, r *Report) error {
+	writer := csv.NewWriter(w)
+	columns := slices.Concat(pageColumns, metricColumns(r.Pages), extraColumns(r.Pages))
+
+	// Write CSV header
+	header := make([]string, 0, len(columns))
+	for _, column := range columns {
+		header = append(header, column.name)
+	}
+	err := writer.Write(header)
+	if err != nil {
+		return err
+	}
+	for _, pageData := range r.Pages {
+		row := make([]string, 0, len(columns))
+		for _, column := range columns {
+			row = append(row, column.value(pageData))
+		}
+		err := writer.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteSkippedCSV lists the URLs blocked by robots.txt.
+func WriteSkippedCSV(w io.Writer, r *Report) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write([]string{"page_url", "reason"})
+	if err != nil {
+		return err
+	}
+	for _, skippedURL := range r.Skipped {
+		err := writer.Write([]string{skippedURL, "robots.txt"})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteSitemapCSV lists the sitemap coverage issues.
+func WriteSitemapCSV(w io.Writer, r *Report) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write([]string{"page_url", "issue"})
+	if err != nil {
+		return err
+	}
+	for _, pageURL := range r.Sitemap.NotLinked {
+		err := writer.Write([]string{pageURL, "in_sitemap_not_linked"})
+		if err != nil {
+			return err
+		}
+	}
+	for _, pageURL := range r.Sitemap.NotInSitemap {
+		err := writer.Write([]string{pageURL, "linked_not_in_sitemap"})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteStatusCSV lists every fetched URL with its HTTP status.
+func WriteStatusCSV(w io.Writer, r *Report) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write([]string{"url", "final_url", "status_code", "content_type", "response_time_ms", "error"})
+	if err != nil {
+		return err
+	}
+	for _, result := range r.Fetches {
+		err := writer.Write([]string{
+			result.URL,
+			result.FinalURL,
+			strconv.Itoa(result.StatusCode),
+			result.ContentType,
+			strconv.FormatInt(result.ResponseTime.Milliseconds(), 10),
+			result.Error,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteBrokenLinksCSV writes one row per broken URL and linking page.
+func WriteBrokenLinksCSV(w io.Writer, r *Report) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write([]string{"url", "status_code", "error", "linked_from"})
+	if err != nil {
+		return err
+	}
+	for _, brokenLink := range r.BrokenLinks {
+		linkedFrom := brokenLink.LinkedFrom
+		if len(linkedFrom) == 0 {
+			// a seed URL, nothing links to it
+			linkedFrom = []string{""}
+		}
+		for _, pageURL := range linkedFrom {
+			err := writer.Write([]string{
+				brokenLink.URL,
+				strconv.Itoa(brokenLink.StatusCode),
+				brokenLink.Error,
+				pageURL,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteLinksCSV writes one row per link of every page, anchors and assets
+// alike, with its kind and anchor text.
+func WriteLinksCSV(w io.Writer, r *Report) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write([]string{"page_url", "url", "kind", "asset", "text", "nofollow"})
+	if err != nil {
+		return err
+	}
+	for _, pageData := range r.Pages {
+		for _, link := range pageData.Links {
+			err := writer.Write([]string{
+				pageData.URL,
+				link.URL,
+				string(link.Kind),
+				strconv.FormatBool(link.IsAsset()),
+				link.Text,
+				strconv.FormatBool(link.Nofollow),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteRedirectsCSV writes one row per redirect hop, so a chain of n hops
+// spans n rows sharing the same url.
+func WriteRedirectsCSV(w io.Writer, r *Report) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write([]string{"url", "final_url", "hops", "loop", "hop", "hop_url", "status_code"})
+	if err != nil {
+		return err
+	}
+	for _, result := range r.Redirects {
+		for i, redirect := range result.Redirects {
+			err := writer.Write([]string{
+				result.URL,
+				result.FinalURL,
+				strconv.Itoa(len(result.Redirects)),
+				strconv.FormatBool(result.RedirectLoop),
+				strconv.Itoa(i + 1),
+				redirect.URL,
+				strconv.Itoa(redirect.StatusCode),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteAssetsCSV lists the checked assets with their status, size and
+// issues.
+func WriteAssetsCSV(w io.Writer, r *Report) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write([]string{"url", "kind", "status_code", "content_type", "size", "response_time_ms", "error", "issues", "linked_from", "missing_alt_on"})
+	if err != nil {
+		return err
+	}
+	for _, asset := range r.Assets {
+		err := writer.Write([]string{
+			asset.URL,
+			string(asset.Kind),
+			strconv.Itoa(asset.StatusCode),
+			asset.ContentType,
+			strconv.FormatInt(asset.Size, 10),
+			strconv.FormatInt(asset.ResponseTime.Milliseconds(), 10),
+			asset.Error,
+			joinStrings(asset.Issues),
+			joinStrings(asset.LinkedFrom),
+			joinStrings(asset.MissingAltOn),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteExternalLinksCSV lists the out-of-scope links of the crawled pages.
+// status_code is empty for links that were not checked.
+func WriteExternalLinksCSV(w io.Writer, r *Report) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write([]string{"url", "status_code", "response_time_ms", "error", "linked_from"})
+	if err != nil {
+		return err
+	}
+	for _, link := range r.External {
+		status, responseTime := "", ""
+		if link.Checked {
+			status = strconv.Itoa(link.StatusCode)
+			responseTime = strconv.FormatInt(link.ResponseTime.Milliseconds(), 10)
+		}
+		err := writer.Write([]string{
+			link.URL,
+			status,
+			responseTime,
+			link.Error,
+			joinStrings(link.LinkedFrom),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteChangesCSV lists the pages that are new or changed since the
+// previous crawl.
+func WriteChangesCSV(w io.Writer, r *Report) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write([]string{"url", "final_url", "change", "status_code"})
+	if err != nil {
+		return err
+	}
+	for _, result := range r.Changes {
+		err := writer.Write([]string{result.URL, result.FinalURL, result.Change, strconv.Itoa(result.StatusCode)})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteDuplicatesCSV lists the pages of every duplicate group, one row per
+// page, with the canonical URL the page declares.
+func WriteDuplicatesCSV(w io.Writer, r *Report) error {
+	writer := csv.NewWriter(w)
+	canonicals := make(map[string]string, len(r.Pages))
+	for _, pageData := range r.Pages {
+		canonicals[pageData.URL] = pageData.Canonical
+	}
+
+	err := writer.Write([]string{"group", "kind", "similarity", "page_url", "canonical_url"})
+	if err != nil {
+		return err
+	}
+	for i, group := range r.Duplicates {
+		for _, pageURL := range group.URLs {
+			err := writer.Write([]string{
+				strconv.Itoa(i + 1),
+				group.Kind,
+				strconv.FormatFloat(group.Similarity, 'f', 4, 64),
+				pageURL,
+				canonicals[pageURL],
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,185 @@
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+
+	"crawler/crawl"
+)
+
+// Diff lists what changed between two crawls of a site.
+type Diff struct {
+	Added    []string       `json:"added"`
+	Removed  []string       `json:"removed"`
+	Changed  []FieldChange  `json:"changed"`
+	Links    []LinkChange   `json:"links"`
+	Statuses []StatusChange `json:"statuses"`
+}
+
+// FieldChange is a page whose title, H1 or first paragraph changed.
+type FieldChange struct {
+	URL   string `json:"url"`
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// LinkChange lists the outgoing links a page gained and lost.
+type LinkChange struct {
+	URL     string   `json:"url"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// StatusChange is a URL whose fetch status changed. A status of 0 means
+// the fetch failed without a response, see Error.
+type StatusChange struct {
+	URL       string `json:"url"`
+	OldStatus int    `json:"old_status"`
+	NewStatus int    `json:"new_status"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Empty reports whether the crawls are the same.
+func (d *Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0 &&
+		len(d.Links) == 0 && len(d.Statuses) == 0
+}
+
+// Compare diffs two reports. Pages and fetches are matched by URL.
+func Compare(oldReport, newReport *Report) *Diff {
+	d := &Diff{}
+
+	oldPages := pagesByURL(oldReport.Pages)
+	newPages := pagesByURL(newReport.Pages)
+	for pageURL := range newPages {
+		if _, found := oldPages[pageURL]; !found {
+			d.Added = append(d.Added, pageURL)
+		}
+	}
+	for pageURL := range oldPages {
+		if _, found := newPages[pageURL]; !found {
+			d.Removed = append(d.Removed, pageURL)
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+
+	for _, newPage := range newReport.Pages {
+		oldPage, found := oldPages[newPage.URL]
+		if !found {
+			continue
+		}
+		fields := []struct {
+			name     string
+			old, new string
+		}{
+			{"title", oldPage.Title, newPage.Title},
+			{"h1", oldPage.H1, newPage.H1},
+			{"first_paragraph", oldPage.FirstParagraph, newPage.FirstParagraph},
+		}
+		for _, field := range fields {
+			if field.old != field.new {
+				d.Changed = append(d.Changed, FieldChange{URL: newPage.URL, Field: field.name, Old: field.old, New: field.new})
+			}
+		}
+
+		added, removed := diffStrings(oldPage.OutgoingLinks, newPage.OutgoingLinks)
+		if len(added) > 0 || len(removed) > 0 {
+			d.Links = append(d.Links, LinkChange{URL: newPage.URL, Added: added, Removed: removed})
+		}
+	}
+
+	oldFetches := make(map[string]crawl.FetchResult, len(oldReport.Fetches))
+	for _, result := range oldReport.Fetches {
+		oldFetches[result.URL] = result
+	}
+	for _, result := range newReport.Fetches {
+		oldResult, found := oldFetches[result.URL]
+		if !found || oldResult.StatusCode == result.StatusCode {
+			continue
+		}
+		d.Statuses = append(d.Statuses, StatusChange{
+			URL:       result.URL,
+			OldStatus: oldResult.StatusCode,
+			NewStatus: result.StatusCode,
+			Error:     result.Error,
+		})
+	}
+	sort.Slice(d.Statuses, func(i, j int) bool {
+		return d.Statuses[i].URL < d.Statuses[j].URL
+	})
+	return d
+}
+
+func pagesByURL(pages []crawl.PageData) map[string]crawl.PageData {
+	byURL := make(map[string]crawl.PageData, len(pages))
+	for _, pageData := range pages {
+		byURL[pageData.URL] = pageData
+	}
+	return byURL
+}
+
+// diffStrings returns the sorted values only in b and only in a.
+func diffStrings(a, b []string) (added, removed []string) {
+	inA := make(map[string]struct{}, len(a))
+	for _, value := range a {
+		inA[value] = struct{}{}
+	}
+	inB := make(map[string]struct{}, len(b))
+	for _, value := range b {
+		inB[value] = struct{}{}
+	}
+
+	for value := range inB {
+		if _, found := inA[value]; !found {
+			added = append(added, value)
+		}
+	}
+	for value := range inA {
+		if _, found := inB[value]; !found {
+			removed = append(removed, value)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// WriteText writes the diff for humans: + for added, - for removed and ~
+// for changed.
+func (d *Diff) WriteText(w io.Writer) error {
+	buf := bufio.NewWriter(w)
+
+	fmt.Fprintf(buf, "pages added: %d, removed: %d, changed: %d, with link changes: %d, status changes: %d\n",
+		len(d.Added), len(d.Removed), len(d.Changed), len(d.Links), len(d.Statuses))
+	for _, pageURL := range d.Added {
+		fmt.Fprintf(buf, "+ %s\n", pageURL)
+	}
+	for _, pageURL := range d.Removed {
+		fmt.Fprintf(buf, "- %s\n", pageURL)
+	}
+	for _, change := range d.Changed {
+		fmt.Fprintf(buf, "~ %s %s: %q -> %q\n", change.URL, change.Field, change.Old, change.New)
+	}
+	for _, change := range d.Links {
+		fmt.Fprintf(buf, "~ %s links:\n", change.URL)
+		for _, link := range change.Added {
+			fmt.Fprintf(buf, "    + %s\n", link)
+		}
+		for _, link := range change.Removed {
+			fmt.Fprintf(buf, "    - %s\n", link)
+		}
+	}
+	for _, change := range d.Statuses {
+		fmt.Fprintf(buf, "~ %s status: %d -> %d", change.URL, change.OldStatus, change.NewStatus)
+		if change.Error != "" {
+			fmt.Fprintf(buf, " (%s)", change.Error)
+		}
+		fmt.Fprintf(buf, "\n")
+	}
+
+	return buf.Flush()
+}
@@ -0,0 +1,64 @@
+package report
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"crawler/crawl"
+)
+
+func TestCompare(t *testing.T) {
+	oldReport := testReport()
+	newReport := testReport()
+	newReport.Pages = []crawl.PageData{
+		{
+			URL:            "https://blog.test.dev/a",
+			H1:             "A",
+			Title:          "Page A, updated",
+			FirstParagraph: "First.",
+			OutgoingLinks:  []string{"https://blog.test.dev/d"},
+		},
+		{URL: "https://blog.test.dev/d", H1: "D"},
+	}
+	newReport.Fetches = []crawl.FetchResult{
+		{URL: "https://blog.test.dev/a", StatusCode: 200},
+		{URL: "https://blog.test.dev/c", StatusCode: 500, Error: "received status code 500"},
+	}
+
+	d := Compare(oldReport, newReport)
+
+	expected := &Diff{
+		Added:   []string{"https://blog.test.dev/d"},
+		Removed: []string{"https://blog.test.dev/b"},
+		Changed: []FieldChange{{URL: "https://blog.test.dev/a", Field: "title", Old: "Page A", New: "Page A, updated"}},
+		Links:   []LinkChange{{URL: "https://blog.test.dev/a", Added: []string{"https://blog.test.dev/d"}}},
+		Statuses: []StatusChange{
+			{URL: "https://blog.test.dev/c", OldStatus: 404, NewStatus: 500, Error: "received status code 500"},
+		},
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("expected %+v, got %+v", expected, d)
+	}
+
+	var buf bytes.Buffer
+	if err := d.WriteText(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedText := "pages added: 1, removed: 1, changed: 1, with link changes: 1, status changes: 1\n" +
+		"+ https://blog.test.dev/d\n" +
+		"- https://blog.test.dev/b\n" +
+		"~ https://blog.test.dev/a title: \"Page A\" -> \"Page A, updated\"\n" +
+		"~ https://blog.test.dev/a links:\n" +
+		"    + https://blog.test.dev/d\n" +
+		"~ https://blog.test.dev/c status: 404 -> 500 (received status code 500)\n"
+	if buf.String() != expectedText {
+		t.Errorf("expected %q, got %q", expectedText, buf.String())
+	}
+}
+
+func TestCompareSame(t *testing.T) {
+	if d := Compare(testReport(), testReport()); !d.Empty() {
+		t.Errorf("expected no differences, got %+v", d)
+	}
+}
@@ -0,0 +1,176 @@
+package report
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"crawler/crawl"
+)
+
+var graphWriters = map[string]func(io.Writer, *Report) error{
+	".dot":     WriteGraphDOT,
+	".gv":      WriteGraphDOT,
+	".graphml": WriteGraphML,
+	".json":    WriteGraphJSON,
+}
+
+// GraphWriterFor picks the link graph format from the extension of path:
+// .dot or .gv, .graphml or .json.
+func GraphWriterFor(path string) (func(io.Writer, *Report) error, error) {
+	write, found := graphWriters[strings.ToLower(filepath.Ext(path))]
+	if !found {
+		return nil, fmt.Errorf("can't tell the link graph format of %s, use .dot, .graphml or .json", path)
+	}
+	return write, nil
+}
+
+var errNoGraph = errors.New("report has no link graph")
+
+// WriteGraphDOT writes the link graph for Graphviz, with the page metrics
+// as node attributes.
+func WriteGraphDOT(w io.Writer, r *Report) error {
+	if r.Graph == nil {
+		return errNoGraph
+	}
+	buf := bufio.NewWriter(w)
+
+	fmt.Fprintf(buf, "digraph crawl {\n")
+	for _, pageURL := range r.Graph.Pages {
+		metrics := r.Graph.Metrics[pageURL]
+		fmt.Fprintf(buf, "  %s [in_degree=%d, out_degree=%d, click_depth=%d, pagerank=%g, orphan=%t];\n",
+			dotID(pageURL), metrics.InDegree, metrics.OutDegree, metrics.Depth, metrics.PageRank, metrics.Orphan)
+	}
+	for _, pageURL := range r.Graph.Pages {
+		for _, target := range r.Graph.Links[pageURL] {
+			fmt.Fprintf(buf, "  %s -> %s;\n", dotID(pageURL), dotID(target))
+		}
+	}
+	fmt.Fprintf(buf, "}\n")
+
+	return buf.Flush()
+}
+
+// dotID quotes s as a DOT identifier.
+func dotID(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML writes the link graph as GraphML, with the page metrics as
+// node data.
+func WriteGraphML(w io.Writer, r *Report) error {
+	if r.Graph == nil {
+		return errNoGraph
+	}
+
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "in_degree", For: "node", Name: "in_degree", Type: "int"},
+			{ID: "out_degree", For: "node", Name: "out_degree", Type: "int"},
+			{ID: "click_depth", For: "node", Name: "click_depth", Type: "int"},
+			{ID: "pagerank", For: "node", Name: "pagerank", Type: "double"},
+			{ID: "orphan", For: "node", Name: "orphan", Type: "boolean"},
+		},
+		Graph: graphMLGraph{ID: "crawl", EdgeDefault: "directed"},
+	}
+	for _, pageURL := range r.Graph.Pages {
+		metrics := r.Graph.Metrics[pageURL]
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: pageURL,
+			Data: []graphMLData{
+				{Key: "in_degree", Value: strconv.Itoa(metrics.InDegree)},
+				{Key: "out_degree", Value: strconv.Itoa(metrics.OutDegree)},
+				{Key: "click_depth", Value: strconv.Itoa(metrics.Depth)},
+				{Key: "pagerank", Value: strconv.FormatFloat(metrics.PageRank, 'g', -1, 64)},
+				{Key: "orphan", Value: strconv.FormatBool(metrics.Orphan)},
+			},
+		})
+		for _, target := range r.Graph.Links[pageURL] {
+			doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{Source: pageURL, Target: target})
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type graphJSONNode struct {
+	URL string `json:"url"`
+	crawl.PageMetrics
+}
+
+// WriteGraphJSON writes the link graph as JSON: the start page, the pages
+// with their metrics and an adjacency list keyed by page URL.
+func WriteGraphJSON(w io.Writer, r *Report) error {
+	if r.Graph == nil {
+		return errNoGraph
+	}
+
+	nodes := make([]graphJSONNode, 0, len(r.Graph.Pages))
+	adjacency := make(map[string][]string, len(r.Graph.Pages))
+	for _, pageURL := range r.Graph.Pages {
+		nodes = append(nodes, graphJSONNode{URL: pageURL, PageMetrics: r.Graph.Metrics[pageURL]})
+		// an empty list rather than null for pages without links
+		adjacency[pageURL] = append([]string{}, r.Graph.Links[pageURL]...)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Start     string              `json:"start"`
+		Nodes     []graphJSONNode     `json:"nodes"`
+		Adjacency map[string][]string `json:"adjacency"`
+	}{r.Graph.Start, nodes, adjacency})
+}
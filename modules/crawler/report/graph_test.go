@@ -0,0 +1,141 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+
+	"crawler/crawl"
+)
+
+func testGraphReport() *Report {
+	r := testReport()
+	r.Graph = &crawl.LinkGraph{
+		Start: "https://blog.test.dev/a",
+		Pages: []string{"https://blog.test.dev/a", "https://blog.test.dev/b"},
+		Links: map[string][]string{"https://blog.test.dev/b": {"https://blog.test.dev/a"}},
+		Metrics: map[string]crawl.PageMetrics{
+			"https://blog.test.dev/a": {InDegree: 1, Depth: 0, PageRank: 0.75},
+			"https://blog.test.dev/b": {OutDegree: 1, Depth: -1, PageRank: 0.25, Orphan: true},
+		},
+	}
+	for i, pageData := range r.Pages {
+		metrics := r.Graph.Metrics[pageData.URL]
+		r.Pages[i].Metrics = &metrics
+	}
+	return r
+}
+
+func TestWriteGraphDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGraphDOT(&buf, testGraphReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "digraph crawl {\n" +
+		"  \"https://blog.test.dev/a\" [in_degree=1, out_degree=0, click_depth=0, pagerank=0.75, orphan=false];\n" +
+		"  \"https://blog.test.dev/b\" [in_degree=0, out_degree=1, click_depth=-1, pagerank=0.25, orphan=true];\n" +
+		"  \"https://blog.test.dev/b\" -> \"https://blog.test.dev/a\";\n" +
+		"}\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestWriteGraphML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGraphML(&buf, testGraphReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var doc graphML
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(doc.Graph.Nodes) != 2 || len(doc.Graph.Edges) != 1 || doc.Graph.EdgeDefault != "directed" {
+		t.Errorf("unexpected graph %+v", doc.Graph)
+	}
+	edge := graphMLEdge{Source: "https://blog.test.dev/b", Target: "https://blog.test.dev/a"}
+	if doc.Graph.Edges[0] != edge {
+		t.Errorf("expected %+v, got %+v", edge, doc.Graph.Edges[0])
+	}
+	if data := doc.Graph.Nodes[1].Data; data[3].Key != "pagerank" || data[3].Value != "0.25" || data[4].Value != "true" {
+		t.Errorf("unexpected node data %+v", data)
+	}
+}
+
+func TestWriteGraphJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGraphJSON(&buf, testGraphReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded struct {
+		Start     string              `json:"start"`
+		Nodes     []graphJSONNode     `json:"nodes"`
+		Adjacency map[string][]string `json:"adjacency"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedAdjacency := map[string][]string{
+		"https://blog.test.dev/a": {},
+		"https://blog.test.dev/b": {"https://blog.test.dev/a"},
+	}
+	if decoded.Start != "https://blog.test.dev/a" || !reflect.DeepEqual(decoded.Adjacency, expectedAdjacency) {
+		t.Errorf("unexpected graph %+v", decoded)
+	}
+	if len(decoded.Nodes) != 2 || decoded.Nodes[1].URL != "https://blog.test.dev/b" || !decoded.Nodes[1].Orphan {
+		t.Errorf("unexpected nodes %+v", decoded.Nodes)
+	}
+}
+
+func TestWriteGraphWithoutGraph(t *testing.T) {
+	if err := WriteGraphJSON(&bytes.Buffer{}, testReport()); err == nil {
+		t.Errorf("expected error for a report without link graph, got nil")
+	}
+}
+
+func TestGraphWriterFor(t *testing.T) {
+	for _, path := range []string{"graph.dot", "out/graph.GV", "graph.graphml", "graph.json"} {
+		if _, err := GraphWriterFor(path); err != nil {
+			t.Errorf("%s: unexpected error: %v", path, err)
+		}
+	}
+	if _, err := GraphWriterFor("graph.png"); err == nil {
+		t.Errorf("expected error for unknown extension, got nil")
+	}
+}
+
+func TestPageMetricsInReports(t *testing.T) {
+	r := testGraphReport()
+
+	var csvOut bytes.Buffer
+	if err := (csvWriter{}).Write(&csvOut, r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(csvOut.String(), "\n")
+	if !strings.Contains(lines[0], ",in_degree,out_degree,click_depth,pagerank,orphan,extra_author,") {
+		t.Errorf("expected metric columns before the extra ones, got %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[2], ",0,1,-1,0.250000,true,,") {
+		t.Errorf("expected the metrics of b, got %q", lines[2])
+	}
+
+	var markdownOut bytes.Buffer
+	if err := (markdownWriter{}).Write(&markdownOut, r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{
+		"## Link graph\n\n| URL | PageRank | Linked from | Links to | Click depth |\n|---|---|---|---|---|\n" +
+			"| https://blog.test.dev/a | 0.7500 | 1 | 0 | 0 |\n| https://blog.test.dev/b | 0.2500 | 0 | 1 | -1 |\n",
+		"## Orphan pages\n\n- https://blog.test.dev/b\n",
+	} {
+		if !strings.Contains(markdownOut.String(), expected) {
+			t.Errorf("expected %q in %q", expected, markdownOut.String())
+		}
+	}
+}
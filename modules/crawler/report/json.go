@@ -0,0 +1,118 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+
+	"crawler/crawl"
+)
+
+type jsonWriter struct{}
+
+// Write writes the whole report as one indented JSON document.
+func (jsonWriter) Write(w io.Writer, r *Report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+type jsonlWriter struct{}
+
+type jsonlPage struct {
+	Type string `json:"type"`
+	crawl.PageData
+}
+
+type jsonlFetch struct {
+	Type string `json:"type"`
+	crawl.FetchResult
+}
+
+type jsonlBrokenLink struct {
+	Type string `json:"type"`
+	crawl.BrokenLink
+}
+
+type jsonlAsset struct {
+	Type string `json:"type"`
+	crawl.AssetResult
+}
+
+type jsonlExternal struct {
+	Type string `json:"type"`
+	crawl.ExternalLink
+}
+
+type jsonlDuplicate struct {
+	Type string `json:"type"`
+	crawl.DuplicateGroup
+}
+
+type jsonlURL struct {
+	Type  string `json:"type"`
+	URL   string `json:"url"`
+	Issue string `json:"issue"`
+}
+
+// Write writes one JSON object per line, pages first. Every line carries a
+// "type" field so consumers can process the file as a stream.
+func (jsonlWriter) Write(w io.Writer, r *Report) error {
+	encoder := json.NewEncoder(w)
+
+	for _, pageData := range r.Pages {
+		if err := encoder.Encode(jsonlPage{Type: "page", PageData: pageData}); err != nil {
+			return err
+		}
+	}
+	for _, result := range r.Fetches {
+		if err := encoder.Encode(jsonlFetch{Type: "fetch", FetchResult: result}); err != nil {
+			return err
+		}
+	}
+	for _, brokenLink := range r.BrokenLinks {
+		if err := encoder.Encode(jsonlBrokenLink{Type: "broken_link", BrokenLink: brokenLink}); err != nil {
+			return err
+		}
+	}
+	for _, result := range r.Redirects {
+		if err := encoder.Encode(jsonlFetch{Type: "redirect", FetchResult: result}); err != nil {
+			return err
+		}
+	}
+	for _, asset := range r.Assets {
+		if err := encoder.Encode(jsonlAsset{Type: "asset", AssetResult: asset}); err != nil {
+			return err
+		}
+	}
+	for _, link := range r.External {
+		if err := encoder.Encode(jsonlExternal{Type: "external_link", ExternalLink: link}); err != nil {
+			return err
+		}
+	}
+	for _, result := range r.Changes {
+		if err := encoder.Encode(jsonlFetch{Type: "change", FetchResult: result}); err != nil {
+			return err
+		}
+	}
+	for _, group := range r.Duplicates {
+		if err := encoder.Encode(jsonlDuplicate{Type: "duplicate", DuplicateGroup: group}); err != nil {
+			return err
+		}
+	}
+	for _, skippedURL := range r.Skipped {
+		if err := encoder.Encode(jsonlURL{Type: "skipped", URL: skippedURL, Issue: "robots.txt"}); err != nil {
+			return err
+		}
+	}
+	for _, pageURL := range r.Sitemap.NotLinked {
+		if err := encoder.Encode(jsonlURL{Type: "sitemap", URL: pageURL, Issue: "in_sitemap_not_linked"}); err != nil {
+			return err
+		}
+	}
+	for _, pageURL := range r.Sitemap.NotInSitemap {
+		if err := encoder.Encode(jsonlURL{Type: "sitemap", URL: pageURL, Issue: "linked_not_in_sitemap"}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"crawler/crawl"
+)
+
+type markdownWriter struct{}
+
+// Write renders a human-readable summary with one table row per page.
+func (markdownWriter) Write(w io.Writer, r *Report) error {
+	buf := bufio.NewWriter(w)
+
+	fmt.Fprintf(buf, "# Crawl report: %s\n\n", r.BaseURL)
+	fmt.Fprintf(buf, "- Pages: %d\n", len(r.Pages))
+	fmt.Fprintf(buf, "- Fetched URLs: %d\n", len(r.Fetches))
+	fmt.Fprintf(buf, "- Broken links: %d\n", len(r.BrokenLinks))
+	fmt.Fprintf(buf, "- Redirected URLs: %d\n", len(r.Redirects))
+	fmt.Fprintf(buf, "- Skipped by robots.txt: %d\n", len(r.Skipped))
+	fmt.Fprintf(buf, "- In sitemap but never linked: %d\n", len(r.Sitemap.NotLinked))
+	fmt.Fprintf(buf, "- Linked but missing from sitemap: %d\n", len(r.Sitemap.NotInSitemap))
+	if len(r.Assets) > 0 {
+		fmt.Fprintf(buf, "- Checked assets: %d\n", len(r.Assets))
+	}
+	if len(r.External) > 0 {
+		fmt.Fprintf(buf, "- External links: %d\n", len(r.External))
+	}
+	if len(r.Changes) > 0 {
+		fmt.Fprintf(buf, "- New or changed since the last crawl: %d\n", len(r.Changes))
+	}
+	if len(r.Duplicates) > 0 {
+		fmt.Fprintf(buf, "- Duplicate content groups: %d\n", len(r.Duplicates))
+	}
+
+	fmt.Fprintf(buf, "\n## Pages\n\n")
+	fmt.Fprintf(buf, "| URL | Title | H1 | First paragraph | Words | Links | Images | Canonical | Alternates |\n")
+	fmt.Fprintf(buf, "|---|---|---|---|---|---|---|---|---|\n")
+	for _, pageData := range r.Pages {
+		fmt.Fprintf(buf, "| %s | %s | %s | %s | %d | %d | %d | %s | %s |\n",
+			markdownCell(pageData.URL),
+			markdownCell(pageData.Title),
+			markdownCell(pageData.H1),
+			markdownCell(pageData.FirstParagraph),
+			pageData.WordCount,
+			len(pageData.OutgoingLinks),
+			len(pageData.ImageURLs),
+			markdownCell(pageData.Canonical),
+			markdownCell(strings.Join(pageData.Alternates, ", ")),
+		)
+	}
+
+	fmt.Fprintf(buf, "\n## Page details\n")
+	for _, pageData := range r.Pages {
+		writeMarkdownPageDetails(buf, pageData)
+	}
+
+	if r.Graph != nil && len(r.Graph.Pages) > 0 {
+		writeMarkdownLinkGraph(buf, r.Graph)
+	}
+
+	if len(r.BrokenLinks) > 0 {
+		fmt.Fprintf(buf, "\n## Broken links\n\n")
+		fmt.Fprintf(buf, "| URL | Status | Error | Linked from |\n")
+		fmt.Fprintf(buf, "|---|---|---|---|\n")
+		for _, brokenLink := range r.BrokenLinks {
+			fmt.Fprintf(buf, "| %s | %d | %s | %s |\n",
+				markdownCell(brokenLink.URL),
+				brokenLink.StatusCode,
+				markdownCell(brokenLink.Error),
+				markdownCell(strings.Join(brokenLink.LinkedFrom, ", ")),
+			)
+		}
+	}
+
+	if len(r.Redirects) > 0 {
+		fmt.Fprintf(buf, "\n## Redirects\n\n")
+		fmt.Fprintf(buf, "| URL | Final URL | Hops | Loop | Chain |\n")
+		fmt.Fprintf(buf, "|---|---|---|---|---|\n")
+		for _, result := range r.Redirects {
+			chain := make([]string, 0, len(result.Redirects))
+			for _, redirect := range result.Redirects {
+				chain = append(chain, fmt.Sprintf("%d", redirect.StatusCode))
+			}
+			fmt.Fprintf(buf, "| %s | %s | %d | %t | %s |\n",
+				markdownCell(result.URL),
+				markdownCell(result.FinalURL),
+				len(result.Redirects),
+				result.RedirectLoop,
+				strings.Join(chain, " → "),
+			)
+		}
+	}
+
+	if len(r.Assets) > 0 {
+		fmt.Fprintf(buf, "\n## Assets\n\n")
+		fmt.Fprintf(buf, "| URL | Kind | Status | Content type | Size | Issues | Linked from |\n")
+		fmt.Fprintf(buf, "|---|---|---|---|---|---|---|\n")
+		for _, asset := range r.Assets {
+			fmt.Fprintf(buf, "| %s | %s | %d | %s | %d | %s | %s |\n",
+				markdownCell(asset.URL),
+				asset.Kind,
+				asset.StatusCode,
+				markdownCell(asset.ContentType),
+				asset.Size,
+				strings.Join(asset.Issues, ", "),
+				markdownCell(strings.Join(asset.LinkedFrom, ", ")),
+			)
+		}
+	}
+
+	if len(r.External) > 0 {
+		fmt.Fprintf(buf, "\n## External links\n\n")
+		fmt.Fprintf(buf, "| URL | Status | Error | Linked from |\n")
+		fmt.Fprintf(buf, "|---|---|---|---|\n")
+		for _, link := range r.External {
+			status := "not checked"
+			if link.Checked {
+				status = strconv.Itoa(link.StatusCode)
+			}
+			fmt.Fprintf(buf, "| %s | %s | %s | %s |\n",
+				markdownCell(link.URL),
+				status,
+				markdownCell(link.Error),
+				markdownCell(strings.Join(link.LinkedFrom, ", ")),
+			)
+		}
+	}
+
+	if len(r.Changes) > 0 {
+		fmt.Fprintf(buf, "\n## Changed since the last crawl\n\n")
+		for _, result := range r.Changes {
+			fmt.Fprintf(buf, "- %s (%s)\n", result.URL, result.Change)
+		}
+	}
+
+	if len(r.Duplicates) > 0 {
+		fmt.Fprintf(buf, "\n## Duplicate content\n\n")
+		fmt.Fprintf(buf, "| Group | Kind | Similarity | Pages |\n")
+		fmt.Fprintf(buf, "|---|---|---|---|\n")
+		for i, group := range r.Duplicates {
+			fmt.Fprintf(buf, "| %d | %s | %.2f | %s |\n",
+				i+1,
+				group.Kind,
+				group.Similarity,
+				markdownCell(strings.Join(group.URLs, ", ")),
+			)
+		}
+	}
+
+	writeMarkdownList(buf, "Skipped by robots.txt", r.Skipped)
+	writeMarkdownList(buf, "In sitemap but never linked", r.Sitemap.NotLinked)
+	writeMarkdownList(buf, "Linked but missing from sitemap", r.Sitemap.NotInSitemap)
+
+	return buf.Flush()
+}
+
+// writeMarkdownPageDetails lists the metadata that doesn't fit the table.
+func writeMarkdownPageDetails(w io.Writer, pageData crawl.PageData) {
+	if pageData.MetaDescription == "" && pageData.MetaRobots == "" && len(pageData.OpenGraph) == 0 &&
+		len(pageData.TwitterCard) == 0 && len(pageData.Hreflang) == 0 && len(pageData.JSONLD) == 0 &&
+		len(pageData.Headings) == 0 && len(pageData.Extra) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n### %s\n\n", pageData.URL)
+	if pageData.MetaDescription != "" {
+		fmt.Fprintf(w, "- Description: %s\n", markdownCell(pageData.MetaDescription))
+	}
+	if pageData.MetaRobots != "" {
+		fmt.Fprintf(w, "- Robots: %s\n", markdownCell(pageData.MetaRobots))
+	}
+	for _, key := range slices.Sorted(maps.Keys(pageData.OpenGraph)) {
+		fmt.Fprintf(w, "- %s: %s\n", key, markdownCell(pageData.OpenGraph[key]))
+	}
+	for _, key := range slices.Sorted(maps.Keys(pageData.TwitterCard)) {
+		fmt.Fprintf(w, "- %s: %s\n", key, markdownCell(pageData.TwitterCard[key]))
+	}
+	for _, alternate := range pageData.Hreflang {
+		fmt.Fprintf(w, "- hreflang %s: %s\n", alternate.Lang, alternate.URL)
+	}
+	for _, block := range pageData.JSONLD {
+		fmt.Fprintf(w, "- JSON-LD: `%s`\n", strings.ReplaceAll(string(block), "`", "'"))
+	}
+	for _, name := range slices.Sorted(maps.Keys(pageData.Extra)) {
+		fmt.Fprintf(w, "- %s: %s\n", name, markdownCell(strings.Join(pageData.Extra[name], ", ")))
+	}
+	if len(pageData.Headings) > 0 {
+		fmt.Fprintf(w, "- Outline:\n")
+		for _, heading := range pageData.Headings {
+			fmt.Fprintf(w, "%s- h%d %s\n", strings.Repeat("  ", heading.Level), heading.Level, markdownCell(heading.Text))
+		}
+	}
+}
+
+// writeMarkdownLinkGraph lists the pages by PageRank and the orphan pages.
+func writeMarkdownLinkGraph(w io.Writer, g *crawl.LinkGraph) {
+	pages := slices.Clone(g.Pages)
+	sort.SliceStable(pages, func(i, j int) bool {
+		return g.Metrics[pages[i]].PageRank > g.Metrics[pages[j]].PageRank
+	})
+
+	fmt.Fprintf(w, "\n## Link graph\n\n")
+	fmt.Fprintf(w, "| URL | PageRank | Linked from | Links to | Click depth |\n")
+	fmt.Fprintf(w, "|---|---|---|---|---|\n")
+	var orphans []string
+	for _, pageURL := range pages {
+		metrics := g.Metrics[pageURL]
+		fmt.Fprintf(w, "| %s | %.4f | %d | %d | %d |\n",
+			markdownCell(pageURL), metrics.PageRank, metrics.InDegree, metrics.OutDegree, metrics.Depth)
+		if metrics.Orphan {
+			orphans = append(orphans, pageURL)
+		}
+	}
+	sort.Strings(orphans)
+	writeMarkdownList(w, "Orphan pages", orphans)
+}
+
+func writeMarkdownList(w io.Writer, title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n## %s\n\n", title)
+	for _, item := range items {
+		fmt.Fprintf(w, "- %s\n", item)
+	}
+}
+
+// markdownCell keeps a value on one line and escapes table pipes.
+func markdownCell(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,258 @@
+package report
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"crawler/crawl"
+)
+
+// Read parses a report written in format. CSV only holds the page report,
+// see ReadStatusCSV for the fetch statuses. Markdown only keeps the pages
+// table, so links and statuses are missing from a report read back from it.
+func Read(r io.Reader, format string) (*Report, error) {
+	switch format {
+	case "csv":
+		return readCSV(r)
+	case "json":
+		var report Report
+		if err := json.NewDecoder(r).Decode(&report); err != nil {
+			return nil, err
+		}
+		return &report, nil
+	case "jsonl":
+		return readJSONL(r)
+	case "markdown", "md":
+		return readMarkdown(r)
+	}
+	return nil, fmt.Errorf("unknown report format %q", format)
+}
+
+// FormatFromPath guesses the format of a report file from its extension.
+func FormatFromPath(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return "csv", nil
+	case ".json":
+		return "json", nil
+	case ".jsonl", ".ndjson":
+		return "jsonl", nil
+	case ".md", ".markdown":
+		return "markdown", nil
+	}
+	return "", fmt.Errorf("can't tell the report format of %s", path)
+}
+
+func readCSV(r io.Reader) (*Report, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[name] = i
+	}
+	if _, found := columns["page_url"]; !found {
+		return nil, errors.New("not a page report: no page_url column")
+	}
+
+	report := &Report{}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		value := func(name string) string {
+			if i, found := columns[name]; found && i < len(row) {
+				return row[i]
+			}
+			return ""
+		}
+		list := func(name string) []string {
+			if value(name) == "" {
+				return nil
+			}
+			return strings.Split(value(name), ";")
+		}
+
+		wordCount, _ := strconv.Atoi(value("word_count"))
+		report.Pages = append(report.Pages, crawl.PageData{
+			URL:             value("page_url"),
+			H1:              value("h1"),
+			FirstParagraph:  value("first_paragraph"),
+			OutgoingLinks:   list("outgoing_link_urls"),
+			ImageURLs:       list("image_urls"),
+			Canonical:       value("canonical_url"),
+			Alternates:      list("alternate_urls"),
+			Title:           value("title"),
+			MetaDescription: value("meta_description"),
+			MetaRobots:      value("meta_robots"),
+			WordCount:       wordCount,
+		})
+	}
+	return report, nil
+}
+
+// ReadStatusCSV adds the fetches of a status.csv side report to r.
+func ReadStatusCSV(in io.Reader, r *Report) error {
+	reader := csv.NewReader(in)
+	if _, err := reader.Read(); err != nil {
+		return err
+	}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(row) < 6 {
+			return fmt.Errorf("status report row has %d columns, expected 6", len(row))
+		}
+		statusCode, _ := strconv.Atoi(row[2])
+		r.Fetches = append(r.Fetches, crawl.FetchResult{
+			URL:         row[0],
+			FinalURL:    row[1],
+			StatusCode:  statusCode,
+			ContentType: row[3],
+			Error:       row[5],
+		})
+	}
+}
+
+func readJSONL(r io.Reader) (*Report, error) {
+	report := &Report{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64<<20)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		var record jsonlURL
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, err
+		}
+
+		var err error
+		switch record.Type {
+		case "page":
+			var pageData crawl.PageData
+			err = json.Unmarshal(line, &pageData)
+			report.Pages = append(report.Pages, pageData)
+		case "fetch", "redirect", "change":
+			var result crawl.FetchResult
+			err = json.Unmarshal(line, &result)
+			switch record.Type {
+			case "fetch":
+				report.Fetches = append(report.Fetches, result)
+			case "redirect":
+				report.Redirects = append(report.Redirects, result)
+			default:
+				report.Changes = append(report.Changes, result)
+			}
+		case "broken_link":
+			var brokenLink crawl.BrokenLink
+			err = json.Unmarshal(line, &brokenLink)
+			report.BrokenLinks = append(report.BrokenLinks, brokenLink)
+		case "asset":
+			var asset crawl.AssetResult
+			err = json.Unmarshal(line, &asset)
+			report.Assets = append(report.Assets, asset)
+		case "external_link":
+			var link crawl.ExternalLink
+			err = json.Unmarshal(line, &link)
+			report.External = append(report.External, link)
+		case "duplicate":
+			var group crawl.DuplicateGroup
+			err = json.Unmarshal(line, &group)
+			report.Duplicates = append(report.Duplicates, group)
+		case "skipped":
+			report.Skipped = append(report.Skipped, record.URL)
+		case "sitemap":
+			if record.Issue == "in_sitemap_not_linked" {
+				report.Sitemap.NotLinked = append(report.Sitemap.NotLinked, record.URL)
+			} else {
+				report.Sitemap.NotInSitemap = append(report.Sitemap.NotInSitemap, record.URL)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return report, scanner.Err()
+}
+
+// readMarkdown reads the pages table of a Markdown report.
+func readMarkdown(r io.Reader) (*Report, error) {
+	report := &Report{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64<<20)
+	inPages := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if title, found := strings.CutPrefix(line, "# Crawl report: "); found {
+			report.BaseURL = title
+		}
+		if strings.HasPrefix(line, "## ") {
+			inPages = line == "## Pages"
+			continue
+		}
+		if !inPages || !strings.HasPrefix(line, "| ") || strings.HasPrefix(line, "| URL |") {
+			continue
+		}
+
+		cells := splitMarkdownRow(line)
+		if len(cells) < 9 {
+			continue
+		}
+		wordCount, _ := strconv.Atoi(cells[4])
+		var alternates []string
+		if cells[8] != "" {
+			alternates = strings.Split(cells[8], ", ")
+		}
+		report.Pages = append(report.Pages, crawl.PageData{
+			URL:            cells[0],
+			Title:          cells[1],
+			H1:             cells[2],
+			FirstParagraph: cells[3],
+			WordCount:      wordCount,
+			Canonical:      cells[7],
+			Alternates:     alternates,
+		})
+	}
+	return report, scanner.Err()
+}
+
+// splitMarkdownRow splits a table row written with markdownCell.
+func splitMarkdownRow(line string) []string {
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "| "), " |")
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case strings.HasPrefix(line[i:], " | "):
+			cells = append(cells, cell.String())
+			cell.Reset()
+			i += 2
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, cell.String())
+}
@@ -0,0 +1,113 @@
+package report
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadRoundTrip(t *testing.T) {
+	tests := []struct {
+		format    string
+		withLinks bool
+	}{
+		{format: "csv", withLinks: true},
+		{format: "json", withLinks: true},
+		{format: "jsonl", withLinks: true},
+		{format: "markdown", withLinks: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			written := testReport()
+			writer, err := NewWriter(tc.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var buf bytes.Buffer
+			if err := writer.Write(&buf, written); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			read, err := Read(&buf, tc.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(read.Pages) != len(written.Pages) {
+				t.Fatalf("expected %d pages, got %d", len(written.Pages), len(read.Pages))
+			}
+			for i, page := range read.Pages {
+				expected := written.Pages[i]
+				if page.URL != expected.URL || page.Title != expected.Title || page.H1 != expected.H1 ||
+					page.FirstParagraph != expected.FirstParagraph || page.WordCount != expected.WordCount {
+					t.Errorf("expected %+v, got %+v", expected, page)
+				}
+				if tc.withLinks && !reflect.DeepEqual(page.OutgoingLinks, expected.OutgoingLinks) {
+					t.Errorf("expected links %v, got %v", expected.OutgoingLinks, page.OutgoingLinks)
+				}
+			}
+		})
+	}
+}
+
+func TestReadJSONLSections(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (jsonlWriter{}).Write(&buf, testReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	read, err := Read(&buf, "jsonl")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	written := testReport()
+	if !reflect.DeepEqual(read.Fetches, written.Fetches) || !reflect.DeepEqual(read.Skipped, written.Skipped) ||
+		!reflect.DeepEqual(read.Sitemap, written.Sitemap) || !reflect.DeepEqual(read.Duplicates, written.Duplicates) ||
+		!reflect.DeepEqual(read.External, written.External) ||
+		len(read.BrokenLinks) != 1 || len(read.Assets) != 1 {
+		t.Errorf("expected every section to be read back, got %+v", read)
+	}
+}
+
+func TestReadStatusCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteStatusCSV(&buf, testReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r := &Report{}
+	if err := ReadStatusCSV(&buf, r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(r.Fetches) != 2 || r.Fetches[1].StatusCode != 404 || r.Fetches[1].Error != "received status code 404" {
+		t.Errorf("unexpected fetches: %+v", r.Fetches)
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"out/report.csv", "csv"},
+		{"report.JSON", "json"},
+		{"report.jsonl", "jsonl"},
+		{"report.md", "markdown"},
+	}
+	for _, tc := range tests {
+		actual, err := FormatFromPath(tc.path)
+		if err != nil || actual != tc.expected {
+			t.Errorf("%s: expected %q, got %q (%v)", tc.path, tc.expected, actual, err)
+		}
+	}
+	if _, err := FormatFromPath("report.xml"); err == nil {
+		t.Errorf("expected error for unknown extension, got nil")
+	}
+}
+
+func TestReadUnknownFormat(t *testing.T) {
+	if _, err := Read(strings.NewReader(""), "xml"); err == nil {
+		t.Errorf("expected error for unknown format, got nil")
+	}
+}
@@ -0,0 +1,102 @@
+// Package report writes crawl results in CSV, JSON, JSONL and Markdown.
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"crawler/crawl"
+)
+
+// Report is everything a crawl produced, with pages sorted by URL so the
+// output is deterministic.
+type Report struct {
+	BaseURL     string                 `json:"base_url"`
+	Pages       []crawl.PageData       `json:"pages"`
+	Fetches     []crawl.FetchResult    `json:"fetches"`
+	BrokenLinks []crawl.BrokenLink     `json:"broken_links"`
+	Redirects   []crawl.FetchResult    `json:"redirects"`
+	Skipped     []string               `json:"skipped"`
+	Sitemap     SitemapCoverage        `json:"sitemap"`
+	Assets      []crawl.AssetResult    `json:"assets,omitempty"`
+	External    []crawl.ExternalLink   `json:"external_links,omitempty"`
+	Changes     []crawl.FetchResult    `json:"changes,omitempty"`
+	Duplicates  []crawl.DuplicateGroup `json:"duplicates,omitempty"`
+	Graph       *crawl.LinkGraph       `json:"-"` // exported on its own, see WriteGraphDOT
+}
+
+// SitemapCoverage compares the sitemap with the crawled link graph.
+type SitemapCoverage struct {
+	NotLinked    []string `json:"not_linked"`
+	NotInSitemap []string `json:"not_in_sitemap"`
+}
+
+// Writer renders a report in one format.
+type Writer interface {
+	Write(w io.Writer, r *Report) error
+}
+
+var writers = map[string]Writer{
+	"csv":      csvWriter{},
+	"json":     jsonWriter{},
+	"jsonl":    jsonlWriter{},
+	"markdown": markdownWriter{},
+	"md":       markdownWriter{},
+}
+
+// NewWriter returns the writer for a format name.
+func NewWriter(format string) (Writer, error) {
+	writer, found := writers[format]
+	if !found {
+		return nil, fmt.Errorf("unknown report format %q", format)
+	}
+	return writer, nil
+}
+
+// Formats lists the supported format names.
+func Formats() []string {
+	formats := make([]string, 0, len(writers))
+	for format := range writers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// Build collects the results of a finished crawl.
+func Build(c *crawl.Crawler) *Report {
+	r := New(c.BaseURL().String(), c.Pages())
+	r.Fetches = c.Fetches()
+	r.BrokenLinks = c.BrokenLinks()
+	r.Redirects = c.Redirects()
+	r.Skipped = c.Skipped()
+	r.Sitemap.NotLinked, r.Sitemap.NotInSitemap = c.SitemapCoverage()
+	r.Assets = c.Assets()
+	r.External = c.ExternalLinks()
+	r.Changes = c.Changes()
+	r.Duplicates = c.Duplicates()
+	r.Graph = c.LinkGraph()
+	for i, pageData := range r.Pages {
+		if metrics, found := r.Graph.Metrics[pageData.URL]; found {
+			r.Pages[i].Metrics = &metrics
+		}
+	}
+	return r
+}
+
+// New builds a report holding the given pages, sorted by URL.
+func New(baseURL string, pages map[string]crawl.PageData) *Report {
+	sortedPages := make([]crawl.PageData, 0, len(pages))
+	for _, pageData := range pages {
+		sortedPages = append(sortedPages, pageData)
+	}
+	sort.Slice(sortedPages, func(i, j int) bool {
+		return sortedPages[i].URL < sortedPages[j].URL
+	})
+
+	return &Report{
+		BaseURL: baseURL,
+		Pages:   sortedPages,
+	}
+}
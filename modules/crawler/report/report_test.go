@@ -0,0 +1,287 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"crawler/crawl"
+)
+
+func testReport() *Report {
+	pages := map[string]crawl.PageData{
+		"blog.test.dev/b": {
+			URL:           "https://blog.test.dev/b",
+			H1:            "B | second",
+			OutgoingLinks: []string{"https://blog.test.dev/a", "https://blog.test.dev/c"},
+			Links: []crawl.Link{
+				{URL: "https://blog.test.dev/a", Kind: crawl.LinkAnchor, Text: "A, first"},
+				{URL: "https://blog.test.dev/c", Kind: crawl.LinkAnchor, Nofollow: true},
+				{URL: "https://blog.test.dev/site.css", Kind: crawl.LinkStylesheet},
+			},
+		},
+		"blog.test.dev/a": {
+			URL:             "https://blog.test.dev/a",
+			H1:              "A",
+			FirstParagraph:  "First.",
+			ImageURLs:       []string{"https://blog.test.dev/a.png"},
+			Canonical:       "https://blog.test.dev/a",
+			Alternates:      []string{"https://blog.test.dev/a-old"},
+			Title:           "Page A",
+			MetaDescription: "About A.",
+			WordCount:       3,
+			Headings:        []crawl.Heading{{Level: 1, Text: "A"}, {Level: 2, Text: "More"}},
+			OpenGraph:       map[string]string{"og:title": "A", "og:image": "https://blog.test.dev/a.png"},
+			JSONLD:          []json.RawMessage{json.RawMessage(`{"@type":"Article"}`)},
+			Extra:           map[string][]string{"author": {"Ada"}, "tags": {"go", "web"}},
+		},
+	}
+	r := New("https://blog.test.dev", pages)
+	r.Fetches = []crawl.FetchResult{
+		{URL: "https://blog.test.dev/a", FinalURL: "https://blog.test.dev/a", StatusCode: 200, ContentType: "text/html"},
+		{URL: "https://blog.test.dev/c", FinalURL: "https://blog.test.dev/c", StatusCode: 404, Error: "received status code 404"},
+	}
+	r.BrokenLinks = []crawl.BrokenLink{
+		{FetchResult: r.Fetches[1], LinkedFrom: []string{"https://blog.test.dev/b"}},
+	}
+	r.Redirects = []crawl.FetchResult{
+		{
+			URL:        "https://blog.test.dev/a-old",
+			FinalURL:   "https://blog.test.dev/a",
+			StatusCode: 200,
+			Redirects: []crawl.Redirect{
+				{URL: "https://blog.test.dev/a-old", StatusCode: 301},
+				{URL: "https://blog.test.dev/a-older", StatusCode: 302},
+			},
+		},
+	}
+	r.Assets = []crawl.AssetResult{
+		{
+			URL:          "https://blog.test.dev/a.png",
+			Kind:         crawl.LinkImage,
+			StatusCode:   200,
+			ContentType:  "image/png",
+			Size:         2 << 20,
+			LinkedFrom:   []string{"https://blog.test.dev/a"},
+			MissingAltOn: []string{"https://blog.test.dev/a"},
+			Issues:       []string{crawl.AssetOversized, crawl.AssetMissingAlt},
+		},
+	}
+	r.External = []crawl.ExternalLink{
+		{URL: "https://docs.example.org/", Checked: true, StatusCode: 404, LinkedFrom: []string{"https://blog.test.dev/a", "https://blog.test.dev/b"}},
+		{URL: "https://example.org/", LinkedFrom: []string{"https://blog.test.dev/b"}},
+	}
+	r.Changes = []crawl.FetchResult{
+		{URL: "https://blog.test.dev/a", FinalURL: "https://blog.test.dev/a", StatusCode: 200, Change: crawl.ChangeModified},
+	}
+	r.Duplicates = []crawl.DuplicateGroup{
+		{Kind: crawl.DuplicateNear, Similarity: 0.96875, URLs: []string{"https://blog.test.dev/a", "https://blog.test.dev/b"}},
+	}
+	r.Skipped = []string{"https://blog.test.dev/private"}
+	r.Sitemap.NotInSitemap = []string{"https://blog.test.dev/b"}
+	return r
+}
+
+func TestNewSortsPages(t *testing.T) {
+	r := testReport()
+	if len(r.Pages) != 2 || r.Pages[0].URL != "https://blog.test.dev/a" || r.Pages[1].URL != "https://blog.test.dev/b" {
+		t.Errorf("expected pages sorted by URL, got %+v", r.Pages)
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (csvWriter{}).Write(&buf, testReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "page_url,h1,first_paragraph,outgoing_link_urls,image_urls,canonical_url,alternate_urls," +
+		"title,meta_description,meta_robots,word_count,headings,hreflang,open_graph,twitter_card,json_ld,extra_author,extra_tags\n" +
+		"https://blog.test.dev/a,A,First.,,https://blog.test.dev/a.png,https://blog.test.dev/a,https://blog.test.dev/a-old," +
+		"Page A,About A.,,3,h1=A;h2=More,,og:image=https://blog.test.dev/a.png;og:title=A,,\"[{\"\"@type\"\":\"\"Article\"\"}]\",Ada,go;web\n" +
+		"https://blog.test.dev/b,B | second,,https://blog.test.dev/a;https://blog.test.dev/c,,,,,,,0,,,,,,,\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (jsonWriter{}).Write(&buf, testReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(decoded.Pages) != 2 || len(decoded.Pages[1].OutgoingLinks) != 2 {
+		t.Errorf("expected links to survive as a list, got %+v", decoded.Pages)
+	}
+	if len(decoded.Skipped) != 1 {
+		t.Errorf("expected 1 skipped URL, got %v", decoded.Skipped)
+	}
+}
+
+func TestJSONLWriter(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (jsonlWriter{}).Write(&buf, testReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	expectedTypes := []string{"page", "page", "fetch", "fetch", "broken_link", "redirect", "asset", "external_link", "external_link", "change", "duplicate", "skipped", "sitemap"}
+	if len(lines) != len(expectedTypes) {
+		t.Fatalf("expected %d lines, got %d", len(expectedTypes), len(lines))
+	}
+	for i, line := range lines {
+		var record struct {
+			Type string   `json:"type"`
+			URL  string   `json:"url"`
+			URLs []string `json:"urls"`
+		}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("line %d: unexpected error: %v", i, err)
+		}
+		if record.Type != expectedTypes[i] || (record.URL == "" && len(record.URLs) == 0) {
+			t.Errorf("line %d: expected type %q with a URL, got %+v", i, expectedTypes[i], record)
+		}
+	}
+}
+
+func TestMarkdownWriter(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (markdownWriter{}).Write(&buf, testReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, `| https://blog.test.dev/b |  | B \| second |  | 0 | 2 | 0 |  |  |`) {
+		t.Errorf("expected escaped page row, got:\n%s", output)
+	}
+	if strings.Index(output, "blog.test.dev/a |") > strings.Index(output, "blog.test.dev/b |") {
+		t.Errorf("expected pages sorted by URL, got:\n%s", output)
+	}
+	if !strings.Contains(output, "- og:title: A\n") || !strings.Contains(output, "    - h2 More\n") ||
+		!strings.Contains(output, "- tags: go, web\n") {
+		t.Errorf("expected page details, got:\n%s", output)
+	}
+	if !strings.Contains(output, "| https://blog.test.dev/c | 404 | received status code 404 | https://blog.test.dev/b |") {
+		t.Errorf("expected broken link row, got:\n%s", output)
+	}
+	if !strings.Contains(output, "| https://blog.test.dev/a.png | image | 200 | image/png | 2097152 | oversized, missing_alt | https://blog.test.dev/a |") {
+		t.Errorf("expected asset row, got:\n%s", output)
+	}
+	if !strings.Contains(output, "- https://blog.test.dev/a (changed)\n") {
+		t.Errorf("expected changed page, got:\n%s", output)
+	}
+	if !strings.Contains(output, "| 1 | near | 0.97 | https://blog.test.dev/a, https://blog.test.dev/b |") {
+		t.Errorf("expected duplicate group row, got:\n%s", output)
+	}
+	if !strings.Contains(output, "## Skipped by robots.txt") {
+		t.Errorf("expected skipped section, got:\n%s", output)
+	}
+}
+
+func TestNewWriterUnknownFormat(t *testing.T) {
+	if _, err := NewWriter("xml"); err == nil {
+		t.Errorf("expected error for unknown format, got nil")
+	}
+}
+
+func TestWriteBrokenLinksCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteBrokenLinksCSV(&buf, testReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "url,status_code,error,linked_from\n" +
+		"https://blog.test.dev/c,404,received status code 404,https://blog.test.dev/b\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestWriteRedirectsCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteRedirectsCSV(&buf, testReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "url,final_url,hops,loop,hop,hop_url,status_code\n" +
+		"https://blog.test.dev/a-old,https://blog.test.dev/a,2,false,1,https://blog.test.dev/a-old,301\n" +
+		"https://blog.test.dev/a-old,https://blog.test.dev/a,2,false,2,https://blog.test.dev/a-older,302\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestWriteLinksCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteLinksCSV(&buf, testReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "page_url,url,kind,asset,text,nofollow\n" +
+		"https://blog.test.dev/b,https://blog.test.dev/a,anchor,false,\"A, first\",false\n" +
+		"https://blog.test.dev/b,https://blog.test.dev/c,anchor,false,,true\n" +
+		"https://blog.test.dev/b,https://blog.test.dev/site.css,stylesheet,true,,false\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestWriteAssetsCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteAssetsCSV(&buf, testReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "url,kind,status_code,content_type,size,response_time_ms,error,issues,linked_from,missing_alt_on\n" +
+		"https://blog.test.dev/a.png,image,200,image/png,2097152,0,,oversized;missing_alt,https://blog.test.dev/a,https://blog.test.dev/a\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestWriteExternalLinksCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteExternalLinksCSV(&buf, testReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "url,status_code,response_time_ms,error,linked_from\n" +
+		"https://docs.example.org/,404,0,,https://blog.test.dev/a;https://blog.test.dev/b\n" +
+		"https://example.org/,,,,https://blog.test.dev/b\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestWriteChangesCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteChangesCSV(&buf, testReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "url,final_url,change,status_code\n" +
+		"https://blog.test.dev/a,https://blog.test.dev/a,changed,200\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestWriteDuplicatesCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteDuplicatesCSV(&buf, testReport()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "group,kind,similarity,page_url,canonical_url\n" +
+		"1,near,0.9688,https://blog.test.dev/a,https://blog.test.dev/a\n" +
+		"1,near,0.9688,https://blog.test.dev/b,\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
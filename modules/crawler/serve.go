@@ -0,0 +1,622 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"crawler/crawl"
+	"crawler/report"
+)
+
+// maxJobSize limits the JSON body of a submitted job.
+const maxJobSize = 1 << 20
+
+// rejectedJobKeys are the config keys a job can't set, in the order they
+// are checked. The file settings would let any API client read and write
+// files on the server; the others only apply to the command line, where
+// reports and logs are written locally.
+var rejectedJobKeys = []struct{ key, reason string }{
+	{"cache_dir", "server files can't be set through the API"},
+	{"checkpoint", "server files can't be set through the API"},
+	{"checkpoint_interval", "server files can't be set through the API"},
+	{"resume", "server files can't be set through the API"},
+	{"warc", "server files can't be set through the API"},
+	{"output", "reports are downloaded from the job"},
+	{"format", "reports are downloaded from the job"},
+	{"graph", "reports are downloaded from the job"},
+	{"stats_json", "statistics are downloaded from the job"},
+	{"quiet", "it only applies to the command line"},
+	{"metrics_addr", "metrics are served by the server"},
+	{"log_level", "logging is set by the server"},
+	{"log_format", "logging is set by the server"},
+}
+
+// runServe implements "crawler serve [flags]": an HTTP API that runs crawl
+// jobs until the process is stopped.
+func runServe(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("crawler serve", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: crawler serve [flags]\n\n")
+		fmt.Fprintf(fs.Output(), "Jobs are submitted as JSON with the keys of a --config file, except for\n")
+		fmt.Fprintf(fs.Output(), "the file, report and logging settings.\n\n")
+		fs.PrintDefaults()
+	}
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	maxWorkers := fs.Int("max-workers", 20, "crawl workers shared by all running jobs")
+	keepJobs := fs.Int("keep-jobs", 100, "stopped jobs kept with their reports, older ones are dropped")
+	logLevel := fs.String("log-level", "info", "log level: debug, info (every fetched page), warn or error")
+	logFormat := fs.String("log-format", "text", "log format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return errors.New("serve takes no arguments")
+	}
+	if *maxWorkers <= 0 {
+		return errors.New("invalid max workers value")
+	}
+	if *keepJobs < 0 {
+		return errors.New("invalid keep jobs value")
+	}
+	logger, err := newLogger(os.Stderr, *logLevel, *logFormat)
+	if err != nil {
+		return err
+	}
+
+	// on Ctrl-C cancel every job and stop serving
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	server := newJobServer(ctx, *maxWorkers, *keepJobs, logger)
+	httpServer := &http.Server{Handler: server.handler()}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.Serve(listener)
+	}()
+	fmt.Fprintf(stdout, "serving crawl jobs on http://%s with %d workers\n", listener.Addr(), *maxWorkers)
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	fmt.Fprintf(stdout, "shutting down\n")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = httpServer.Shutdown(shutdownCtx)
+	server.wait()
+	return err
+}
+
+// workerBudget hands out crawl workers to jobs. A job takes all the workers
+// it needs before it starts, one job at a time, so jobs start in the order
+// they ask and a large job is not starved by smaller ones.
+type workerBudget struct {
+	size  int
+	turn  chan struct{}
+	slots chan struct{}
+}
+
+func newWorkerBudget(size int) *workerBudget {
+	return &workerBudget{
+		size:  size,
+		turn:  make(chan struct{}, 1),
+		slots: make(chan struct{}, size),
+	}
+}
+
+// acquire blocks until n workers are free or ctx is done. n must not be
+// over the size of the budget.
+func (b *workerBudget) acquire(ctx context.Context, n int) error {
+	select {
+	case b.turn <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-b.turn }()
+
+	for i := range n {
+		select {
+		case b.slots <- struct{}{}:
+		case <-ctx.Done():
+			b.release(i)
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (b *workerBudget) release(n int) {
+	for range n {
+		<-b.slots
+	}
+}
+
+type jobStatus string
+
+const (
+	jobQueued    jobStatus = "queued"
+	jobRunning   jobStatus = "running"
+	jobFinished  jobStatus = "finished"
+	jobCancelled jobStatus = "cancelled"
+	jobFailed    jobStatus = "failed"
+)
+
+// job is one crawl submitted to the server.
+type job struct {
+	id      string
+	cfg     cliConfig
+	workers int // taken from the budget, see jobWorkers
+	crawler *crawl.Crawler
+	cancel  context.CancelFunc
+	created time.Time
+	logger  *slog.Logger
+
+	mu       sync.Mutex
+	status   jobStatus
+	err      string
+	started  time.Time
+	finished time.Time
+	report   *report.Report // set once the crawl has run
+}
+
+// jobInfo is the JSON view of a job.
+type jobInfo struct {
+	ID         string         `json:"id"`
+	URL        string         `json:"url"`
+	Status     jobStatus      `json:"status"`
+	Error      string         `json:"error,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	StartedAt  time.Time      `json:"started_at,omitzero"`
+	FinishedAt time.Time      `json:"finished_at,omitzero"`
+	Progress   crawl.Progress `json:"progress"`
+	Summary    *jobSummary    `json:"summary,omitempty"`
+}
+
+// jobSummary counts the results of a job that has a report.
+type jobSummary struct {
+	Pages         int `json:"pages"`
+	Fetches       int `json:"fetches"`
+	BrokenLinks   int `json:"broken_links"`
+	Redirects     int `json:"redirects"`
+	ExternalLinks int `json:"external_links"`
+	Duplicates    int `json:"duplicates"`
+	Skipped       int `json:"skipped"`
+}
+
+func (j *job) info() jobInfo {
+	progress := j.crawler.Progress()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	info := jobInfo{
+		ID:         j.id,
+		URL:        j.cfg.URL,
+		Status:     j.status,
+		Error:      j.err,
+		CreatedAt:  j.created,
+		StartedAt:  j.started,
+		FinishedAt: j.finished,
+		Progress:   progress,
+	}
+	if rep := j.report; rep != nil {
+		info.Summary = &jobSummary{
+			Pages:         len(rep.Pages),
+			Fetches:       len(rep.Fetches),
+			BrokenLinks:   len(rep.BrokenLinks),
+			Redirects:     len(rep.Redirects),
+			ExternalLinks: len(rep.External),
+			Duplicates:    len(rep.Duplicates),
+			Skipped:       len(rep.Skipped),
+		}
+	}
+	return info
+}
+
+func (j *job) setStatus(status jobStatus, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status = status
+	if err != nil {
+		j.err = err.Error()
+	}
+	switch status {
+	case jobRunning:
+		j.started = time.Now()
+	case jobFinished, jobCancelled, jobFailed:
+		j.finished = time.Now()
+	}
+}
+
+// done reports whether the job has stopped for good.
+func (j *job) done() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status != jobQueued && j.status != jobRunning
+}
+
+// jobWorkers is the number of workers a job takes from the budget: its
+// largest pool. The asset and the external link checks run after the
+// crawl, one after the other, on the workers the job holds.
+func jobWorkers(cfg cliConfig) int {
+	workers := cfg.Concurrency
+	if cfg.CheckAssets {
+		workers = max(workers, cfg.AssetConcurrency)
+	}
+	if cfg.CheckExternal {
+		workers = max(workers, cfg.ExternalConcurrency)
+	}
+	return workers
+}
+
+// jobServer runs crawl jobs in the background and serves their state,
+// reports and metrics over HTTP. Stopped jobs are kept in memory until
+// they are deleted or more than keepJobs have stopped since.
+type jobServer struct {
+	ctx      context.Context // cancels every job when done
+	budget   *workerBudget
+	keepJobs int
+	logger   *slog.Logger
+	metrics  *crawl.Metrics // shared by all jobs
+	wg       sync.WaitGroup
+
+	mu     sync.Mutex
+	jobs   map[string]*job
+	order  []*job // in submission order
+	nextID int
+}
+
+func newJobServer(ctx context.Context, maxWorkers, keepJobs int, logger *slog.Logger) *jobServer {
+	return &jobServer{
+		ctx:      ctx,
+		budget:   newWorkerBudget(maxWorkers),
+		keepJobs: keepJobs,
+		logger:   logger,
+		metrics:  crawl.NewMetrics(),
+		jobs:     make(map[string]*job),
+	}
+}
+
+// wait blocks until every job has stopped.
+func (s *jobServer) wait() {
+	s.wg.Wait()
+}
+
+func (s *jobServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", s.handleSubmit)
+	mux.HandleFunc("GET /jobs", s.handleList)
+	mux.HandleFunc("GET /jobs/{id}", s.handleGet)
+	mux.HandleFunc("DELETE /jobs/{id}", s.handleDelete)
+	mux.HandleFunc("GET /jobs/{id}/stats", s.handleStats)
+	mux.HandleFunc("POST /jobs/{id}/cancel", s.handleCancel)
+	mux.HandleFunc("GET /jobs/{id}/report", s.handleReport)
+	mux.HandleFunc("GET /jobs/{id}/report/{file}", s.handleSideReport)
+	mux.Handle("GET /metrics", s.metrics)
+	return mux
+}
+
+// submit validates a job config and starts the job.
+func (s *jobServer) submit(cfg cliConfig) (*job, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("no website provided")
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	workers := jobWorkers(cfg)
+	if workers > s.budget.size {
+		return nil, fmt.Errorf("the job needs %d workers, over the budget of %d", workers, s.budget.size)
+	}
+	opts, err := cfg.crawlOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := strconv.Itoa(s.nextID + 1)
+	logger := s.logger.With("job", id)
+	crawler, err := crawl.New(cfg.URL, append(opts, crawl.WithLogger(logger), crawl.WithMetrics(s.metrics))...)
+	if err != nil {
+		return nil, err
+	}
+	s.nextID++
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	j := &job{
+		id:      id,
+		cfg:     cfg,
+		workers: workers,
+		crawler: crawler,
+		cancel:  cancel,
+		created: time.Now(),
+		logger:  logger,
+		status:  jobQueued,
+	}
+	s.jobs[j.id] = j
+	s.order = append(s.order, j)
+	logger.Info("job submitted", "url", cfg.URL, "workers", workers)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+		s.run(ctx, j)
+		s.evict()
+	}()
+	return j, nil
+}
+
+// run waits for workers from the budget, crawls and builds the report. A
+// cancelled crawl keeps the partial report.
+func (s *jobServer) run(ctx context.Context, j *job) {
+	if err := s.budget.acquire(ctx, j.workers); err != nil {
+		j.setStatus(jobCancelled, nil)
+		j.logger.Info("job stopped", "status", jobCancelled)
+		return
+	}
+	defer s.budget.release(j.workers)
+
+	j.setStatus(jobRunning, nil)
+	j.logger.Info("job started")
+	err := j.crawler.Run(ctx)
+	rep := report.Build(j.crawler)
+	j.mu.Lock()
+	j.report = rep
+	j.mu.Unlock()
+
+	switch {
+	case ctx.Err() != nil:
+		j.setStatus(jobCancelled, nil)
+	case err != nil:
+		j.setStatus(jobFailed, err)
+	default:
+		j.setStatus(jobFinished, nil)
+	}
+	info := j.info()
+	j.logger.Info("job stopped", "status", info.Status, "pages", info.Progress.Pages, "duration", info.Progress.Elapsed)
+}
+
+// evict drops the oldest stopped jobs over keepJobs.
+func (s *jobServer) evict() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stopped := 0
+	evicted := make(map[*job]bool)
+	for _, j := range slices.Backward(s.order) {
+		if j.done() {
+			stopped++
+			if stopped > s.keepJobs {
+				evicted[j] = true
+				delete(s.jobs, j.id)
+			}
+		}
+	}
+	s.order = slices.DeleteFunc(s.order, func(j *job) bool { return evicted[j] })
+}
+
+// remove drops a job from the server. s.mu must be held.
+func (s *jobServer) remove(j *job) {
+	delete(s.jobs, j.id)
+	s.order = slices.DeleteFunc(s.order, func(other *job) bool { return other == j })
+}
+
+func (s *jobServer) job(id string) (*job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, found := s.jobs[id]
+	return j, found
+}
+
+func (s *jobServer) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	// a JSON body can't be sent cross-origin by a browser without a preflight
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, errors.New("jobs must be submitted as application/json"))
+		return
+	}
+	cfg, err := decodeJob(http.MaxBytesReader(w, r.Body, maxJobSize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid job: %w", err))
+		return
+	}
+	j, err := s.submit(cfg)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.Header().Set("Location", "/jobs/"+j.id)
+	writeJSON(w, http.StatusCreated, j.info())
+}
+
+// decodeJob reads the config of a submitted job over the defaults. Keys
+// a job can't set are an error, see rejectedJobKeys.
+func decodeJob(r io.Reader) (cliConfig, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return cliConfig{}, err
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(body, &keys); err != nil {
+		return cliConfig{}, err
+	}
+	for _, rejected := range rejectedJobKeys {
+		if _, found := keys[rejected.key]; found {
+			return cliConfig{}, fmt.Errorf("%q is not accepted in a job, %s", rejected.key, rejected.reason)
+		}
+	}
+	cfg := defaultCLIConfig()
+	if err := decodeConfig(bytes.NewReader(body), &cfg); err != nil {
+		return cliConfig{}, err
+	}
+	return cfg, nil
+}
+
+func (s *jobServer) handleList(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	jobs := append([]*job(nil), s.order...)
+	s.mu.Unlock()
+
+	infos := make([]jobInfo, 0, len(jobs))
+	for _, j := range jobs {
+		infos = append(infos, j.info())
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (s *jobServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	j, found := s.job(r.PathValue("id"))
+	if !found {
+		writeError(w, http.StatusNotFound, errors.New("job not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, j.info())
+}
+
+// handleDelete drops a stopped job and its report.
+func (s *jobServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, found := s.jobs[r.PathValue("id")]
+	if !found {
+		writeError(w, http.StatusNotFound, errors.New("job not found"))
+		return
+	}
+	if !j.done() {
+		writeError(w, http.StatusConflict, fmt.Errorf("job is %s, cancel it first", j.info().Status))
+		return
+	}
+	s.remove(j)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleStats writes the statistics of the job so far, see crawl.Stats.
+func (s *jobServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	j, found := s.job(r.PathValue("id"))
+	if !found {
+		writeError(w, http.StatusNotFound, errors.New("job not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, j.crawler.Stats())
+}
+
+func (s *jobServer) handleCancel(w http.ResponseWriter, r *http.Request) {
+	j, found := s.job(r.PathValue("id"))
+	if !found {
+		writeError(w, http.StatusNotFound, errors.New("job not found"))
+		return
+	}
+	if j.done() {
+		writeError(w, http.StatusConflict, fmt.Errorf("job is already %s", j.info().Status))
+		return
+	}
+	j.cancel()
+	writeJSON(w, http.StatusAccepted, j.info())
+}
+
+// reportContentTypes maps report formats to their media types.
+var reportContentTypes = map[string]string{
+	"csv":      "text/csv; charset=utf-8",
+	"json":     "application/json",
+	"jsonl":    "application/jsonl",
+	"markdown": "text/markdown; charset=utf-8",
+	"md":       "text/markdown; charset=utf-8",
+}
+
+// handleReport writes the report of a stopped job in the format of the
+// format query parameter, JSON by default.
+func (s *jobServer) handleReport(w http.ResponseWriter, r *http.Request) {
+	rep, ok := s.jobReport(w, r)
+	if !ok {
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	writer, err := report.NewWriter(format)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeReport(w, reportContentTypes[format], rep, writer.Write)
+}
+
+// handleSideReport writes one of the CSV files that go next to a CSV
+// report, such as status.csv.
+func (s *jobServer) handleSideReport(w http.ResponseWriter, r *http.Request) {
+	rep, ok := s.jobReport(w, r)
+	if !ok {
+		return
+	}
+	for _, side := range sideReports(rep) {
+		if side.name == r.PathValue("file") {
+			writeReport(w, reportContentTypes["csv"], rep, side.write)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, errors.New("unknown report file"))
+}
+
+// jobReport looks up the report of the job in the request path. It writes
+// the error response and returns false if there is none yet.
+func (s *jobServer) jobReport(w http.ResponseWriter, r *http.Request) (*report.Report, bool) {
+	j, found := s.job(r.PathValue("id"))
+	if !found {
+		writeError(w, http.StatusNotFound, errors.New("job not found"))
+		return nil, false
+	}
+	j.mu.Lock()
+	rep, status := j.report, j.status
+	j.mu.Unlock()
+	if rep == nil || !j.done() {
+		writeError(w, http.StatusConflict, fmt.Errorf("job is %s, it has no report", status))
+		return nil, false
+	}
+	return rep, true
+}
+
+// writeReport renders the report before sending anything, so a failure
+// still gets an error status.
+func writeReport(w http.ResponseWriter, contentType string, rep *report.Report, write func(io.Writer, *report.Report) error) {
+	var buf bytes.Buffer
+	if err := write(&buf, rep); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(buf.Bytes())
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"crawler/crawl"
+	"crawler/report"
+)
+
+// newFakeSite serves /, linking to /a and /b, and /slow, which only
+// answers once release is closed or the request is cancelled.
+func newFakeSite(t *testing.T, release chan struct{}) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	page := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprintf(w, "<html><body><h1>%s</h1>%s</body></html>", r.URL.Path, body)
+		}
+	}
+	mux.HandleFunc("/{$}", page(`<a href="/a">a</a><a href="/b">b</a>`))
+	mux.HandleFunc("/a", page(`<a href="/missing">missing</a>`))
+	mux.HandleFunc("/b", page(""))
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		page("")(w, r)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestJobServer(t *testing.T, maxWorkers, keepJobs int) *httptest.Server {
+	t.Helper()
+	jobs := newJobServer(t.Context(), maxWorkers, keepJobs, slog.New(slog.DiscardHandler))
+	server := httptest.NewServer(jobs.handler())
+	t.Cleanup(func() {
+		server.Close()
+		jobs.wait()
+	})
+	return server
+}
+
+func submitJob(t *testing.T, server *httptest.Server, body string) jobInfo {
+	t.Helper()
+	response, err := http.Post(server.URL+"/jobs", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusCreated {
+		message, _ := io.ReadAll(response.Body)
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, response.StatusCode, message)
+	}
+	var info jobInfo
+	if err := json.NewDecoder(response.Body).Decode(&info); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return info
+}
+
+func getJSON(t *testing.T, url string, v any) {
+	t.Helper()
+	response, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d for %s, got %d", http.StatusOK, url, response.StatusCode)
+	}
+	if err := json.NewDecoder(response.Body).Decode(v); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// waitForStatus polls a job until it has the status.
+func waitForStatus(t *testing.T, server *httptest.Server, id string, status jobStatus) jobInfo {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var info jobInfo
+		getJSON(t, server.URL+"/jobs/"+id, &info)
+		if info.Status == status {
+			return info
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected job %s to be %s, got %+v", id, status, info)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServeJob(t *testing.T) {
+	site := newFakeSite(t, nil)
+	server := newTestJobServer(t, 4, 10)
+
+	submitted := submitJob(t, server, fmt.Sprintf(`{"url": %q, "delay": "0s", "concurrency": 2}`, site.URL+"/"))
+	if submitted.ID != "1" || submitted.URL != site.URL+"/" {
+		t.Errorf("expected job 1 for %s, got %+v", site.URL+"/", submitted)
+	}
+
+	info := waitForStatus(t, server, submitted.ID, jobFinished)
+	if info.Progress.Pages != 3 || info.Progress.Failed != 1 {
+		t.Errorf("expected 3 pages and 1 failed fetch, got %+v", info.Progress)
+	}
+	if info.Summary == nil || info.Summary.BrokenLinks != 1 {
+		t.Errorf("expected a summary with 1 broken link, got %+v", info.Summary)
+	}
+	if info.StartedAt.IsZero() || info.FinishedAt.Before(info.StartedAt) {
+		t.Errorf("expected start and finish times, got %+v", info)
+	}
+
+	var stats crawl.Stats
+	getJSON(t, server.URL+"/jobs/1/stats", &stats)
+	if stats.Pages != 3 || stats.StatusCodes[404] != 1 {
+		t.Errorf("expected stats for 3 pages and one 404, got %+v", stats)
+	}
+
+	response, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	metrics, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	if !strings.Contains(string(metrics), "crawler_pages_total 3\n") {
+		t.Errorf("expected the metrics to count 3 pages, got\n%s", metrics)
+	}
+
+	var jobs []jobInfo
+	getJSON(t, server.URL+"/jobs", &jobs)
+	if len(jobs) != 1 || jobs[0].ID != submitted.ID {
+		t.Errorf("expected the job in the list, got %+v", jobs)
+	}
+
+	for _, format := range []string{"json", "jsonl", "csv"} {
+		response, err := http.Get(server.URL + "/jobs/1/report?format=" + format)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		rep, err := report.Read(response.Body, format)
+		response.Body.Close()
+		if err != nil {
+			t.Fatalf("unexpected error reading the %s report: %v", format, err)
+		}
+		if len(rep.Pages) != 3 {
+			t.Errorf("expected 3 pages in the %s report, got %d", format, len(rep.Pages))
+		}
+	}
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/jobs/1/report/status.csv", http.StatusOK},
+		{"/jobs/1/report/unknown.csv", http.StatusNotFound},
+		{"/jobs/1/report?format=xml", http.StatusBadRequest},
+		{"/jobs/2", http.StatusNotFound},
+	}
+	for _, tc := range tests {
+		response, err := http.Get(server.URL + tc.path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		response.Body.Close()
+		if response.StatusCode != tc.status {
+			t.Errorf("expected status %d for %s, got %d", tc.status, tc.path, response.StatusCode)
+		}
+	}
+}
+
+func TestServeInvalidJob(t *testing.T) {
+	server := newTestJobServer(t, 4, 10)
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"malformed JSON", `{"url":`},
+		{"unknown key", `{"url": "https://a.dev", "speed": 3}`},
+		{"no URL", `{"concurrency": 2}`},
+		{"invalid option", `{"url": "https://a.dev", "max_pages": 0}`},
+		{"over the budget", `{"url": "https://a.dev", "concurrency": 5}`},
+		{"asset check over the budget", `{"url": "https://a.dev", "concurrency": 2, "check_assets": true, "asset_concurrency": 5}`},
+		{"server file", `{"url": "https://a.dev", "warc": "/etc/crawl.warc"}`},
+		{"checkpoint", `{"url": "https://a.dev", "checkpoint": "state.json", "resume": true}`},
+		{"cache directory", `{"url": "https://a.dev", "cache_dir": "/tmp/cache"}`},
+		{"command line setting", `{"url": "https://a.dev", "output": "report.csv"}`},
+		{"log setting", `{"url": "https://a.dev", "log_level": "debug"}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			response, err := http.Post(server.URL+"/jobs", "application/json", strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			response.Body.Close()
+			if response.StatusCode != http.StatusBadRequest {
+				t.Errorf("expected status %d, got %d", http.StatusBadRequest, response.StatusCode)
+			}
+		})
+	}
+
+	// a browser can post text/plain across origins without a preflight
+	response, err := http.Post(server.URL+"/jobs", "text/plain", strings.NewReader(`{"url": "https://a.dev"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("expected status %d, got %d", http.StatusUnsupportedMediaType, response.StatusCode)
+	}
+}
+
+func TestServeBudgetAndCancel(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	site := newFakeSite(t, release)
+	server := newTestJobServer(t, 2, 10)
+
+	body := fmt.Sprintf(`{"url": %q, "delay": "0s", "concurrency": 2}`, site.URL+"/slow")
+	first := submitJob(t, server, body)
+	second := submitJob(t, server, body)
+	waitForStatus(t, server, first.ID, jobRunning)
+
+	// the first job holds the whole budget until it is cancelled
+	if info := waitForStatus(t, server, second.ID, jobQueued); !info.StartedAt.IsZero() {
+		t.Errorf("expected the second job to wait, got %+v", info)
+	}
+	response, err := http.Get(server.URL + "/jobs/" + second.ID + "/report")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusConflict {
+		t.Errorf("expected status %d for the report of a queued job, got %d", http.StatusConflict, response.StatusCode)
+	}
+
+	response, err = http.Post(server.URL+"/jobs/"+first.ID+"/cancel", "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusAccepted {
+		t.Errorf("expected status %d, got %d", http.StatusAccepted, response.StatusCode)
+	}
+	waitForStatus(t, server, first.ID, jobCancelled)
+	waitForStatus(t, server, second.ID, jobRunning)
+
+	response, err = http.Post(server.URL+"/jobs/"+first.ID+"/cancel", "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusConflict {
+		t.Errorf("expected status %d for a cancelled job, got %d", http.StatusConflict, response.StatusCode)
+	}
+}
+
+func TestServeDeleteAndEvict(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	site := newFakeSite(t, release)
+	server := newTestJobServer(t, 4, 1)
+
+	deleteJob := func(id string) int {
+		request, err := http.NewRequest(http.MethodDelete, server.URL+"/jobs/"+id, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		response.Body.Close()
+		return response.StatusCode
+	}
+	exists := func(id string) bool {
+		response, err := http.Get(server.URL + "/jobs/" + id)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		response.Body.Close()
+		return response.StatusCode == http.StatusOK
+	}
+
+	slow := submitJob(t, server, fmt.Sprintf(`{"url": %q, "delay": "0s", "concurrency": 2}`, site.URL+"/slow"))
+	waitForStatus(t, server, slow.ID, jobRunning)
+	if status := deleteJob(slow.ID); status != http.StatusConflict {
+		t.Errorf("expected status %d for a running job, got %d", http.StatusConflict, status)
+	}
+	response, err := http.Post(server.URL+"/jobs/"+slow.ID+"/cancel", "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+	waitForStatus(t, server, slow.ID, jobCancelled)
+	if status := deleteJob(slow.ID); status != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, status)
+	}
+	if exists(slow.ID) {
+		t.Errorf("expected job %s to be deleted", slow.ID)
+	}
+	if status := deleteJob(slow.ID); status != http.StatusNotFound {
+		t.Errorf("expected status %d for a deleted job, got %d", http.StatusNotFound, status)
+	}
+
+	// only the last stopped job is kept
+	body := fmt.Sprintf(`{"url": %q, "delay": "0s", "concurrency": 2}`, site.URL+"/")
+	first := submitJob(t, server, body)
+	waitForStatus(t, server, first.ID, jobFinished)
+	second := submitJob(t, server, body)
+	waitForStatus(t, server, second.ID, jobFinished)
+	deadline := time.Now().Add(5 * time.Second)
+	for exists(first.ID) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected job %s to be evicted", first.ID)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	var jobs []jobInfo
+	getJSON(t, server.URL+"/jobs", &jobs)
+	if len(jobs) != 1 || jobs[0].ID != second.ID {
+		t.Errorf("expected only job %s, got %+v", second.ID, jobs)
+	}
+}
@@ -0,0 +1,256 @@
+// Package warc reads and writes WARC 1.1 archives (ISO 28500:2017), one
+// gzip member per record.
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is the version line written at the start of every record.
+const Version = "WARC/1.1"
+
+// MaxContentLength is the largest record block Reader accepts, so a
+// corrupt Content-Length can't exhaust memory.
+const MaxContentLength = 1 << 30
+
+// Record types.
+const (
+	TypeWarcinfo = "warcinfo"
+	TypeRequest  = "request"
+	TypeResponse = "response"
+	TypeMetadata = "metadata"
+)
+
+// Header holds the named fields of a record. Field names are matched case
+// insensitively by Get.
+type Header map[string]string
+
+// Get returns the value of the named field, or "" if it is not set.
+func (h Header) Get(name string) string {
+	if value, found := h[name]; found {
+		return value
+	}
+	for key, value := range h {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// Record is one WARC record: its header fields and its content block.
+type Record struct {
+	Header  Header
+	Content []byte
+}
+
+// Type returns the WARC-Type of the record.
+func (r *Record) Type() string {
+	return r.Header.Get("WARC-Type")
+}
+
+// NewRecordID returns a fresh "<urn:uuid:...>" record ID.
+func NewRecordID() string {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		panic(err)
+	}
+	id[6] = id[6]&0x0f | 0x40 // version 4
+	id[8] = id[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", id[0:4], id[4:6], id[6:8], id[8:10], id[10:])
+}
+
+// Writer writes records to a WARC file.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter returns a Writer that appends gzip-compressed records to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// WriteRecord writes r as its own gzip member. WARC-Record-ID, WARC-Date
+// and WARC-Block-Digest are filled in if r does not set them, and
+// Content-Length always matches the content.
+func (w *Writer) WriteRecord(r *Record) error {
+	if r.Type() == "" {
+		return errors.New("record without WARC-Type")
+	}
+	header := make(Header, len(r.Header)+4)
+	for name, value := range r.Header {
+		header[name] = value
+	}
+	if header.Get("WARC-Record-ID") == "" {
+		header["WARC-Record-ID"] = NewRecordID()
+	}
+	if header.Get("WARC-Date") == "" {
+		header["WARC-Date"] = time.Now().UTC().Format("2006-01-02T15:04:05.000000Z")
+	}
+	if header.Get("WARC-Block-Digest") == "" {
+		sum := sha256.Sum256(r.Content)
+		header["WARC-Block-Digest"] = "sha256:" + base32.StdEncoding.EncodeToString(sum[:])
+	}
+	for name := range header {
+		if strings.EqualFold(name, "Content-Length") {
+			delete(header, name)
+		}
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	fmt.Fprintf(zw, "%s\r\n", Version)
+	for _, name := range fieldOrder(header) {
+		fmt.Fprintf(zw, "%s: %s\r\n", name, header[name])
+	}
+	fmt.Fprintf(zw, "Content-Length: %d\r\n\r\n", len(r.Content))
+	zw.Write(r.Content)
+	fmt.Fprintf(zw, "\r\n\r\n")
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	_, err := w.w.Write(buf.Bytes())
+	return err
+}
+
+// fieldOrder puts WARC-Type, WARC-Record-ID and WARC-Date first and sorts
+// the other fields so records are easy to read and diff.
+func fieldOrder(header Header) []string {
+	first := []string{"WARC-Type", "WARC-Record-ID", "WARC-Date"}
+	var rest []string
+	for name := range header {
+		if !slices.Contains(first, name) {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+
+	var names []string
+	for _, name := range first {
+		if _, found := header[name]; found {
+			names = append(names, name)
+		}
+	}
+	return append(names, rest...)
+}
+
+// Reader reads the records of a WARC file.
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader returns a Reader for a WARC file, gzip-compressed or not.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		// the gzip reader reads the members one after another
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(zr)
+	}
+	return &Reader{r: br}, nil
+}
+
+// Next returns the next record, or io.EOF after the last one.
+func (r *Reader) Next() (*Record, error) {
+	var line string
+	for {
+		var err error
+		line, err = r.readLine()
+		if err == io.EOF && line == "" {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line != "" {
+			break
+		}
+	}
+	if !strings.HasPrefix(line, "WARC/1.") {
+		return nil, fmt.Errorf("expected a WARC version line, got %q", line)
+	}
+
+	record := &Record{Header: make(Header)}
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if line == "" {
+			break
+		}
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid WARC header line %q", line)
+		}
+		record.Header[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+
+	length, err := strconv.ParseInt(record.Header.Get("Content-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q", record.Header.Get("Content-Length"))
+	}
+	if length > MaxContentLength {
+		return nil, fmt.Errorf("record Content-Length %d over the limit of %d bytes", length, MaxContentLength)
+	}
+	// the buffer grows as the content arrives instead of trusting the length
+	var content bytes.Buffer
+	if n, err := content.ReadFrom(io.LimitReader(r.r, length)); err != nil {
+		return nil, err
+	} else if n < length {
+		return nil, io.ErrUnexpectedEOF
+	}
+	record.Content = content.Bytes()
+	// the two CRLFs that end the record
+	for range 2 {
+		if line, err := r.readLine(); err != nil || line != "" {
+			return nil, errors.New("record not terminated by CRLF CRLF")
+		}
+	}
+	return record, nil
+}
+
+func (r *Reader) readLine() (string, error) {
+	line, err := r.r.ReadString('\n')
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Fields parses an application/warc-fields block, e.g. the content of a
+// warcinfo record.
+func Fields(content []byte) Header {
+	fields := make(Header)
+	for _, line := range strings.Split(string(content), "\n") {
+		name, value, found := strings.Cut(strings.TrimRight(line, "\r"), ":")
+		if found {
+			fields[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+	return fields
+}
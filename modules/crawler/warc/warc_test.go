@@ -0,0 +1,122 @@
+package warc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestWriteRead(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	records := []*Record{
+		{Header: Header{"WARC-Type": TypeWarcinfo, "Content-Type": "application/warc-fields"}, Content: []byte("isPartOf: https://blog.test.dev\r\n")},
+		{Header: Header{"WARC-Type": TypeResponse, "WARC-Target-URI": "https://blog.test.dev/", "Content-Length": "1"}, Content: []byte("HTTP/1.1 200 OK\r\n\r\n<h1>Hi</h1>")},
+		{Header: Header{"WARC-Type": TypeMetadata}},
+	}
+	for _, record := range records {
+		if err := w.WriteRecord(record); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// every record is a gzip member of its own
+	zr, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	zr.Multistream(false)
+	first, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(string(first), "WARC/1.1\r\nWARC-Type: warcinfo\r\nWARC-Record-ID: <urn:uuid:") ||
+		!strings.HasSuffix(string(first), "Content-Length: 33\r\n\r\nisPartOf: https://blog.test.dev\r\n\r\n\r\n") {
+		t.Errorf("unexpected first record %q", first)
+	}
+
+	r, err := NewReader(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, expected := range records {
+		record, err := r.Next()
+		if err != nil {
+			t.Fatalf("record %d: unexpected error: %v", i, err)
+		}
+		if record.Type() != expected.Type() || !bytes.Equal(record.Content, expected.Content) {
+			t.Errorf("record %d: expected %q %q, got %q %q", i, expected.Type(), expected.Content, record.Type(), record.Content)
+		}
+		if record.Header.Get("warc-date") == "" || !strings.HasPrefix(record.Header.Get("WARC-Block-Digest"), "sha256:") {
+			t.Errorf("record %d: expected date and digest, got %v", i, record.Header)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("expected %v, got %v", io.EOF, err)
+	}
+}
+
+func TestReadUncompressed(t *testing.T) {
+	input := "WARC/1.0\r\nWARC-Type: response\r\nContent-Length: 5\r\n\r\nhello\r\n\r\n" +
+		"WARC/1.1\r\nWARC-Type: metadata\r\nContent-Length: 0\r\n\r\n\r\n\r\n"
+	r, err := NewReader(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var types []string
+	for {
+		record, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		types = append(types, record.Type())
+	}
+	if strings.Join(types, ",") != "response,metadata" {
+		t.Errorf("expected response and metadata records, got %v", types)
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	tests := map[string]string{
+		"no version":       "HTTP/1.1 200 OK\r\n\r\n",
+		"no length":        "WARC/1.1\r\nWARC-Type: response\r\n\r\n",
+		"short content":    "WARC/1.1\r\nWARC-Type: response\r\nContent-Length: 10\r\n\r\nhello",
+		"not terminated":   "WARC/1.1\r\nWARC-Type: response\r\nContent-Length: 5\r\n\r\nhello world\r\n\r\n",
+		"truncated header": "WARC/1.1\r\nWARC-Type: response\r\n",
+		"oversized":        "WARC/1.1\r\nWARC-Type: response\r\nContent-Length: 1099511627776\r\n\r\nhello",
+		"huge and short":   "WARC/1.1\r\nWARC-Type: response\r\nContent-Length: 1000000000\r\n\r\nhello",
+	}
+	for name, input := range tests {
+		r, err := NewReader(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if _, err := r.Next(); err == nil || err == io.EOF {
+			t.Errorf("%s: expected error, got %v", name, err)
+		}
+	}
+}
+
+func TestNewRecordID(t *testing.T) {
+	id := NewRecordID()
+	if !regexp.MustCompile(`^<urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}>$`).MatchString(id) {
+		t.Errorf("expected a UUID record ID, got %q", id)
+	}
+	if id == NewRecordID() {
+		t.Errorf("expected unique record IDs, got %q twice", id)
+	}
+}
+
+func TestFields(t *testing.T) {
+	fields := Fields([]byte("software: crawler\r\nisPartOf: https://blog.test.dev/\r\n"))
+	if fields.Get("isPartOf") != "https://blog.test.dev/" || fields.Get("software") != "crawler" {
+		t.Errorf("unexpected fields %v", fields)
+	}
+}